@@ -16,13 +16,9 @@ limitations under the License.
 package zendesk
 
 import (
-	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"math/rand"
-	"net/http"
-	"os"
 	"strings"
 	"testing"
 	"time"
@@ -31,64 +27,37 @@ import (
 	"github.com/conduitio/conduit-connector-zendesk/config"
 	"github.com/conduitio/conduit-connector-zendesk/destination"
 	"github.com/conduitio/conduit-connector-zendesk/source"
-	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk/zendesktest"
 
-	"github.com/stretchr/testify/assert"
 	"go.uber.org/goleak"
 )
 
-type ticket struct {
-	ID     int64  `json:"id"`
-	Status string `json:"status"`
-}
-
-var (
-	domain   string
-	userName string
-	apiToken string
-	baseURL  string
+const (
+	userName = "test@testlab.com"
+	apiToken = "dummy_token" //nolint:gosec // credentials of the fake zendesk
 )
 
 func TestAcceptance(t *testing.T) {
-	domain = strings.TrimSpace(os.Getenv("CONDUIT_ZENDESK_DOMAIN"))
-	if domain == "" {
-		t.Error("credentials not set in env CONDUIT_ZENDESK_DOMAIN")
-		t.FailNow()
-	}
-
-	userName = strings.TrimSpace(os.Getenv("CONDUIT_ZENDESK_USER_NAME"))
-	if userName == "" {
-		t.Error("credentials not set in env CONDUIT_ZENDESK_USER_NAME")
-		t.FailNow()
-	}
-
-	apiToken = strings.TrimSpace(os.Getenv("CONDUIT_ZENDESK_API_TOKEN"))
-	if apiToken == "" {
-		t.Error("credentials not set in env CONDUIT_ZENDESK_API_TOKEN")
-		t.FailNow()
-	}
-
-	baseURL = fmt.Sprintf("https://%s.zendesk.com", domain)
+	server := zendesktest.NewServer()
+	defer server.Close()
+	server.SetBasicAuth(userName, apiToken)
 
 	sourceConfig := map[string]string{
-		config.KeyDomain:        domain,
+		config.KeyBaseURL:       server.URL,
 		config.KeyUserName:      userName,
 		config.KeyAPIToken:      apiToken,
 		source.KeyPollingPeriod: "1s",
 	}
 	destConfig := map[string]string{
-		config.KeyDomain:          domain,
+		config.KeyBaseURL:         server.URL,
 		config.KeyUserName:        userName,
 		config.KeyAPIToken:        apiToken,
 		destination.KeyBufferSize: "10",
 	}
 
-	clearTickets := func(t *testing.T) {
-		assert.NoError(t, deleteTickets(t)) // archive the zendesk tickets - max 100 tickets per page
-	}
-
 	sdk.AcceptanceTest(t, AcceptanceTestDriver{
-		rand: rand.New(rand.NewSource(time.Now().UnixNano())), // nolint: gosec // only used for testing
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())), // nolint: gosec // only used for testing
+		server: server,
 		ConfigurableAcceptanceTestDriver: sdk.ConfigurableAcceptanceTestDriver{
 			Config: sdk.ConfigurableAcceptanceTestDriverConfig{
 				Connector: sdk.Connector{
@@ -98,53 +67,86 @@ func TestAcceptance(t *testing.T) {
 				},
 				SourceConfig:      sourceConfig,
 				DestinationConfig: destConfig,
-				BeforeTest: func(t *testing.T) {
-				},
 				GoleakOptions: []goleak.Option{
 					goleak.IgnoreCurrent(),
 					goleak.IgnoreTopFunction("internal/poll.runtime_pollWait"),
 					// keep-alive http connection, will be closed automatically in some time
 					goleak.IgnoreTopFunction("net/http.(*persistConn).writeLoop"),
 				},
-
-				// As we are fetching the deleted tickets as well, The Zendesk API may return the deleted tickets for multiple days,
-				// even if we mark them for permanent deletion. If we use time.Sleep to ensure the newly created tickets are returned by the Zendesk API,
-				// we can't be sure if we will only fetch the newly created tickets or if the deleted tickets will also be returned.
-				// (For ex: while creating a PoC for below-mentioned steps, I received a ticket with ID 135, which had already been deleted, was not visible in console.
-				// For reference, ID of latest created tickets were 735+, and we are regularly deleting the tickets after tests, in subsequent test run)
-				// Regarding skipping the snapshot, We will need to add a new config startTime in source connector which can be used to start
-				// fetching the tickets changes after the current time. The process will look something like below:
-				//
-				// - Add startTime config
-				// - Use the startTime config in iterator
-				// - In Acceptance tests, pass current time as startTime
-				// - Write a new ticket to zendesk (to calculate the ID that will be generated for newly generated tickets)
-				// - Wait for 2 minutes to ensure the newly created ticket is propagated before cleaning up the zendesk tickets and starting the acceptance tests.
-				// - Overwrite the 4 skipped tests, and add 2 minutes sleep time between write and read of the tickets. (Currently we have 5 seconds context timeout)
-				// - Before checking for equality of received and expected tickets, parse record position and ticket data to overwrite the time related fields, to ensure they are ignored while comparing tickets.
-				// - After all the tests that write tickets to zendesk, delete all the tickets (wait 2 minutes before calling delete, wait can be skipped for the overwritten tests)
-				//
-				// Maybe we can write these acceptance tests in next phase when we add startTime in the config parameters.
-				// https://support.zendesk.com/hc/en-us/articles/4599509725466-Removal-of-permanently-deleted-Ticket-IDs
-				Skip: []string{
-					"TestDestination_WriteAsync_Success",
-					"TestSource_Open_ResumeAtPositionCDC",
-					"TestSource_Open_ResumeAtPositionSnapshot",
-					"TestSource_Read_Success",
-				},
 				AfterTest: func(t *testing.T) {
-					clearTickets(t) // clear all tickets from zendesk
+					server.Reset() // clear all tickets from the fake zendesk
 				},
 			},
 		},
 	})
 }
 
+// AcceptanceTestDriver runs the acceptance tests against the fake zendesk server
 type AcceptanceTestDriver struct {
-	rand *rand.Rand
+	rand   *rand.Rand
+	server *zendesktest.Server
 	sdk.ConfigurableAcceptanceTestDriver
 }
 
+// WriteToSource writes the records as tickets using the destination, and returns the records the source should read for them:
+// keyed by the ticket id, with the ticket stored by zendesk as payload
+func (d AcceptanceTestDriver) WriteToSource(t *testing.T, records []sdk.Record) []sdk.Record {
+	records = d.ConfigurableAcceptanceTestDriver.WriteToSource(t, records)
+
+	tickets := make(map[string]map[string]interface{})
+	for _, ticket := range d.server.Tickets() {
+		tickets[fmt.Sprint(ticket["description"])] = ticket
+	}
+
+	want := make([]sdk.Record, 0, len(records))
+	for _, record := range records {
+		ticket, ok := tickets[d.description(t, record)]
+		if !ok {
+			t.Fatalf("ticket not found in zendesk for record %s", record.Payload.Bytes())
+		}
+		payload, err := json.Marshal(ticket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, sdk.Record{
+			Key:     sdk.RawData(fmt.Sprintf("%v", ticket["id"])),
+			Payload: sdk.RawData(payload),
+		})
+	}
+	return want
+}
+
+// ReadFromDestination reads the tickets using the source, and returns the written records in the order their tickets were read
+func (d AcceptanceTestDriver) ReadFromDestination(t *testing.T, records []sdk.Record) []sdk.Record {
+	written := make(map[string]sdk.Record, len(records))
+	for _, record := range records {
+		written[d.description(t, record)] = record
+	}
+
+	got := d.ConfigurableAcceptanceTestDriver.ReadFromDestination(t, records)
+	out := make([]sdk.Record, 0, len(got))
+	for _, record := range got {
+		description := d.description(t, record)
+		r, ok := written[description]
+		if !ok {
+			t.Fatalf("read ticket %s was not written to the destination", record.Key.Bytes())
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// description returns the ticket description of the record payload, the description is unique for every generated record
+func (d AcceptanceTestDriver) description(t *testing.T, record sdk.Record) string {
+	var ticket struct {
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(record.Payload.Bytes(), &ticket); err != nil {
+		t.Fatal(err)
+	}
+	return ticket.Description
+}
+
 func (d AcceptanceTestDriver) GenerateRecord(*testing.T) sdk.Record {
 	payload := fmt.Sprintf(`{"description":"%s","subject":"%s","raw_subject":"%s"}`, d.randString(32), d.randString(32), d.randString(32))
 	return sdk.Record{
@@ -179,47 +181,3 @@ func (d AcceptanceTestDriver) randString(n int) string {
 	}
 	return sb.String()
 }
-
-func deleteTickets(t *testing.T) error {
-	var res ticket
-	cursor := zendesk.NewCursor(userName, apiToken, domain, time.Unix(0, 0))
-	ticketIDs := make([]string, 0)
-
-	// fetching lists of ticket id to delete
-	records, _ := cursor.FetchRecords(context.Background())
-	for _, record := range records {
-		err := json.Unmarshal(record.Payload.Bytes(), &res)
-		if err != nil {
-			return err
-		}
-
-		// skip deleting already deleted tickets
-		if res.Status != "deleted" {
-			id := fmt.Sprint(res.ID)
-			ticketIDs = append(ticketIDs, id)
-		}
-	}
-
-	if len(ticketIDs) != 0 {
-		req, err := http.NewRequestWithContext(
-			context.Background(),
-			http.MethodDelete,
-			fmt.Sprintf("%s/api/v2/tickets/destroy_many?ids=%s", baseURL, strings.Join(ticketIDs, ",")),
-			nil,
-		)
-		assert.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
-		req.Header.Add("Authorization", "Basic "+basicAuth(userName, apiToken))
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		assert.NoError(t, err)
-		assert.NoError(t, resp.Body.Close())
-		client.CloseIdleConnections()
-	}
-	return nil
-}
-
-func basicAuth(username, apiToken string) string {
-	auth := username + "/token:" + apiToken
-	return base64.StdEncoding.EncodeToString([]byte(auth))
-}
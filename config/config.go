@@ -17,47 +17,268 @@ limitations under the License.
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 )
 
 const (
 	KeyDomain   = "zendesk.domain"
+	KeyBaseURL  = "zendesk.baseURL"
+	KeyAuthType = "zendesk.authType"
 	KeyUserName = "zendesk.userName"
 	KeyAPIToken = "zendesk.apiToken" //nolint:gosec //we are not hard coding the credentials
+
+	KeyOAuthAccessToken  = "zendesk.oauth.accessToken" //nolint:gosec //we are not hard coding the credentials
+	KeyOAuthClientID     = "zendesk.oauth.clientID"
+	KeyOAuthClientSecret = "zendesk.oauth.clientSecret" //nolint:gosec //we are not hard coding the credentials
+	KeyOAuthRefreshToken = "zendesk.oauth.refreshToken" //nolint:gosec //we are not hard coding the credentials
+	KeyOAuthScope        = "zendesk.oauth.scope"
+
+	// KeyTimeout is the duration after which a request attempt to zendesk is abandoned
+	KeyTimeout = "zendesk.timeout"
+	// KeyProxyURL is the url of the proxy the requests go through, the proxy environment variables are used if empty
+	KeyProxyURL = "zendesk.proxyURL"
+
+	KeyTLSCAFile     = "zendesk.tls.caFile"
+	KeyTLSCertFile   = "zendesk.tls.certFile"
+	KeyTLSKeyFile    = "zendesk.tls.keyFile"
+	KeyTLSMinVersion = "zendesk.tls.minVersion"
+
+	// AuthTypeBasic authenticates using the username and api token
+	AuthTypeBasic = "basic"
+	// AuthTypeOAuth authenticates using an OAuth access token, or the OAuth client credentials to request one
+	AuthTypeOAuth = "oauth"
+
+	defaultOAuthScope = "read write"
+
+	defaultTimeout = 5 * time.Second
 )
 
+// tlsVersions are the supported values of the tls min version config
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Config is the zendesk account config shared by the source and destination.
+// The credentials may reference secrets, and are resolved using ResolveSecret when used.
 type Config struct {
 	Domain   string
+	BaseURL  string
+	AuthType string
 	UserName string
-	APIToken string
+	APIToken string // secret
+	OAuth    OAuthConfig
+	Timeout  time.Duration // timeout of every request attempt
+	ProxyURL string        // proxy url, empty to use the proxy environment variables
+	TLS      TLSConfig
+}
+
+// TLSConfig holds the TLS settings of the connections to zendesk
+type TLSConfig struct {
+	CAFile     string // PEM bundle of the CAs trusted in addition to the system ones
+	CertFile   string // PEM client certificate, set along with KeyFile
+	KeyFile    string // PEM client private key
+	MinVersion uint16 // minimum TLS version, the go default if 0
 }
 
-// Parse validate zendesk basic token authentication
+// OAuthConfig holds the OAuth credentials, either the AccessToken, or the client credentials
+// used to request access tokens, using the RefreshToken when it is set
+type OAuthConfig struct {
+	AccessToken  string // secret
+	ClientID     string
+	ClientSecret string // secret
+	RefreshToken string // secret file reference, the rotated refresh token is written to
+	Scope        string
+}
+
+// Parse validate zendesk basic token or OAuth authentication
 func Parse(cfg map[string]string) (Config, error) {
+	baseURL, err := parseBaseURL(cfg[KeyBaseURL])
+	if err != nil {
+		return Config{}, err
+	}
+
 	userDomain := cfg[KeyDomain]
-	if userDomain == "" {
+	if userDomain == "" && baseURL == "" {
 		return Config{}, requiredConfigErr(KeyDomain)
 	}
 
-	userName := cfg[KeyUserName]
-	if userName == "" {
-		return Config{}, requiredConfigErr(KeyUserName)
+	authType := cfg[KeyAuthType]
+	if authType == "" {
+		authType = AuthTypeBasic
 	}
 
-	userAPIToken := cfg[KeyAPIToken]
-	if userAPIToken == "" {
-		return Config{}, requiredConfigErr(KeyAPIToken)
+	timeout, err := parseTimeout(cfg[KeyTimeout])
+	if err != nil {
+		return Config{}, err
+	}
+
+	proxyURL, err := parseProxyURL(cfg[KeyProxyURL])
+	if err != nil {
+		return Config{}, err
+	}
+
+	tlsConfig, err := parseTLS(cfg)
+	if err != nil {
+		return Config{}, err
 	}
 
 	config := Config{
 		Domain:   userDomain,
-		UserName: userName,
-		APIToken: userAPIToken,
+		BaseURL:  baseURL,
+		AuthType: authType,
+		Timeout:  timeout,
+		ProxyURL: proxyURL,
+		TLS:      tlsConfig,
+	}
+
+	switch authType {
+	case AuthTypeBasic:
+		config.UserName = cfg[KeyUserName]
+		if config.UserName == "" {
+			return Config{}, requiredConfigErr(KeyUserName)
+		}
+
+		config.APIToken = cfg[KeyAPIToken]
+		if config.APIToken == "" {
+			return Config{}, requiredConfigErr(KeyAPIToken)
+		}
+		if err := validateSecrets(cfg, KeyAPIToken); err != nil {
+			return Config{}, err
+		}
+	case AuthTypeOAuth:
+		oauth, err := parseOAuth(cfg)
+		if err != nil {
+			return Config{}, err
+		}
+		if err := validateSecrets(cfg, KeyOAuthAccessToken, KeyOAuthClientSecret, KeyOAuthRefreshToken); err != nil {
+			return Config{}, err
+		}
+		config.OAuth = oauth
+	default:
+		return Config{}, fmt.Errorf("%q config value should be one of %q or %q, got %q", KeyAuthType, AuthTypeBasic, AuthTypeOAuth, authType)
 	}
 
 	return config, nil
 }
 
+// URL returns the zendesk api url, the base url when it is set, otherwise the url of the domain
+func (c Config) URL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return fmt.Sprintf("https://%s.zendesk.com", c.Domain)
+}
+
+// parseBaseURL validates the base url is an absolute http(s) url, the trailing slash is removed
+func parseBaseURL(baseURL string) (string, error) {
+	if baseURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("%q config value is not a valid url: %w", KeyBaseURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q config value should be an absolute http or https url, got %q", KeyBaseURL, baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%q config value should not contain a query or fragment, got %q", KeyBaseURL, baseURL)
+	}
+	return strings.TrimSuffix(baseURL, "/"), nil
+}
+
+// parseTimeout validates the timeout is a positive duration, defaults to 5s
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return defaultTimeout, nil
+	}
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, fmt.Errorf("%q config value is not a valid duration: %w", KeyTimeout, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("%q config value should be a positive duration, got %q", KeyTimeout, timeout)
+	}
+	return duration, nil
+}
+
+// parseProxyURL validates the proxy url is an absolute http, https or socks5 url
+func parseProxyURL(proxyURL string) (string, error) {
+	if proxyURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return "", fmt.Errorf("%q config value is not a valid url: %w", KeyProxyURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+		return "", fmt.Errorf("%q config value should be an absolute http, https or socks5 url, got %q", KeyProxyURL, proxyURL)
+	}
+	return proxyURL, nil
+}
+
+// parseTLS validates the client certificate and key are set together, and the min version is supported.
+// The files are loaded when the client is created.
+func parseTLS(cfg map[string]string) (TLSConfig, error) {
+	tlsConfig := TLSConfig{
+		CAFile:   cfg[KeyTLSCAFile],
+		CertFile: cfg[KeyTLSCertFile],
+		KeyFile:  cfg[KeyTLSKeyFile],
+	}
+	if tlsConfig.CertFile != "" && tlsConfig.KeyFile == "" {
+		return TLSConfig{}, requiredConfigErr(KeyTLSKeyFile)
+	}
+	if tlsConfig.KeyFile != "" && tlsConfig.CertFile == "" {
+		return TLSConfig{}, requiredConfigErr(KeyTLSCertFile)
+	}
+
+	if minVersion := cfg[KeyTLSMinVersion]; minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return TLSConfig{}, fmt.Errorf("%q config value should be one of \"1.0\", \"1.1\", \"1.2\" or \"1.3\", got %q", KeyTLSMinVersion, minVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	return tlsConfig, nil
+}
+
+// parseOAuth validates either the access token or the client credentials are set
+func parseOAuth(cfg map[string]string) (OAuthConfig, error) {
+	oauth := OAuthConfig{
+		AccessToken:  cfg[KeyOAuthAccessToken],
+		ClientID:     cfg[KeyOAuthClientID],
+		ClientSecret: cfg[KeyOAuthClientSecret],
+		RefreshToken: cfg[KeyOAuthRefreshToken],
+		Scope:        cfg[KeyOAuthScope],
+	}
+	if oauth.AccessToken != "" {
+		return oauth, nil
+	}
+
+	if oauth.ClientID == "" {
+		return OAuthConfig{}, fmt.Errorf("either %q or %q config value must be set", KeyOAuthAccessToken, KeyOAuthClientID)
+	}
+	if oauth.ClientSecret == "" {
+		return OAuthConfig{}, requiredConfigErr(KeyOAuthClientSecret)
+	}
+	if oauth.Scope == "" {
+		oauth.Scope = defaultOAuthScope
+	}
+	// zendesk replaces the refresh token on every renewal, the new one is written back to be used after a restart
+	if _, ok := secretReference(oauth.RefreshToken, SecretFilePrefix); oauth.RefreshToken != "" && !ok {
+		return OAuthConfig{}, fmt.Errorf("%q config value must reference a writable file, i.e. %s/path/to/refresh-token%s, as zendesk rotates the refresh token",
+			KeyOAuthRefreshToken, SecretFilePrefix, SecretSuffix)
+	}
+	return oauth, nil
+}
+
 func requiredConfigErr(name string) error {
 	return fmt.Errorf("%q config value must be set", name)
 }
@@ -17,13 +17,20 @@ limitations under the License.
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestParse(t *testing.T) {
+	refreshToken := filepath.Join(t.TempDir(), "refresh-token")
+	assert.NoError(t, os.WriteFile(refreshToken, []byte("dummy_refresh_token"), 0o600))
+
 	tests := []struct {
 		name    string
 		config  map[string]string
@@ -40,6 +47,8 @@ func TestParse(t *testing.T) {
 			},
 			want: Config{
 				Domain:   "testlab",
+				AuthType: AuthTypeBasic,
+				Timeout:  5 * time.Second,
 				UserName: "test@testlab.com",
 				APIToken: "gkdsaj)({jgo43646435#$!ga",
 			},
@@ -55,6 +64,8 @@ func TestParse(t *testing.T) {
 			},
 			want: Config{
 				Domain:   "testlab",
+				AuthType: AuthTypeBasic,
+				Timeout:  5 * time.Second,
 				UserName: "test@testlab.com",
 				APIToken: "gkdsaj)({jgo43646435#$!ga",
 			},
@@ -100,6 +111,222 @@ func TestParse(t *testing.T) {
 			isError: true,
 			err:     fmt.Errorf("\"zendesk.apiToken\" config value must be set"),
 		},
+		{
+			name: "Login with OAuth access token",
+			config: map[string]string{
+				KeyDomain:           "testlab",
+				KeyAuthType:         AuthTypeOAuth,
+				KeyOAuthAccessToken: "dummy_access_token",
+			},
+			want: Config{
+				Domain:   "testlab",
+				AuthType: AuthTypeOAuth,
+				Timeout:  5 * time.Second,
+				OAuth:    OAuthConfig{AccessToken: "dummy_access_token"},
+			},
+		},
+		{
+			name: "Login with OAuth client credentials",
+			config: map[string]string{
+				KeyDomain:            "testlab",
+				KeyAuthType:          AuthTypeOAuth,
+				KeyOAuthClientID:     "dummy_client",
+				KeyOAuthClientSecret: "dummy_secret",
+				KeyOAuthRefreshToken: "${file:" + refreshToken + "}",
+			},
+			want: Config{
+				Domain:   "testlab",
+				AuthType: AuthTypeOAuth,
+				Timeout:  5 * time.Second,
+				OAuth: OAuthConfig{
+					ClientID:     "dummy_client",
+					ClientSecret: "dummy_secret",
+					RefreshToken: "${file:" + refreshToken + "}",
+					Scope:        "read write",
+				},
+			},
+		},
+		{
+			name: "Login with OAuth without access token and client id",
+			config: map[string]string{
+				KeyDomain:   "testlab",
+				KeyAuthType: AuthTypeOAuth,
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("either \"zendesk.oauth.accessToken\" or \"zendesk.oauth.clientID\" config value must be set"),
+		},
+		{
+			name: "Login with OAuth without client secret",
+			config: map[string]string{
+				KeyDomain:        "testlab",
+				KeyAuthType:      AuthTypeOAuth,
+				KeyOAuthClientID: "dummy_client",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.oauth.clientSecret\" config value must be set"),
+		},
+		{
+			name: "Login with unsupported auth type",
+			config: map[string]string{
+				KeyDomain:   "testlab",
+				KeyAuthType: "saml",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.authType\" config value should be one of \"basic\" or \"oauth\", got \"saml\""),
+		},
+		{
+			name: "Login with base url and without domain",
+			config: map[string]string{
+				KeyBaseURL:  "http://localhost:8080/",
+				KeyUserName: "test@testlab.com",
+				KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+			},
+			want: Config{
+				BaseURL:  "http://localhost:8080",
+				AuthType: AuthTypeBasic,
+				Timeout:  5 * time.Second,
+				UserName: "test@testlab.com",
+				APIToken: "gkdsaj)({jgo43646435#$!ga",
+			},
+		},
+		{
+			name: "Login with relative base url",
+			config: map[string]string{
+				KeyDomain:   "testlab",
+				KeyBaseURL:  "testlab.example.com",
+				KeyUserName: "test@testlab.com",
+				KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.baseURL\" config value should be an absolute http or https url, got \"testlab.example.com\""),
+		},
+		{
+			name: "Login with base url containing a query",
+			config: map[string]string{
+				KeyBaseURL:  "https://testlab.example.com?proxy=true",
+				KeyUserName: "test@testlab.com",
+				KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.baseURL\" config value should not contain a query or fragment, got \"https://testlab.example.com?proxy=true\""),
+		},
+		{
+			name: "Login with timeout, proxy and TLS settings",
+			config: map[string]string{
+				KeyDomain:        "testlab",
+				KeyUserName:      "test@testlab.com",
+				KeyAPIToken:      "gkdsaj)({jgo43646435#$!ga",
+				KeyTimeout:       "1m",
+				KeyProxyURL:      "https://proxy.testlab.com:3128",
+				KeyTLSCAFile:     "/etc/ssl/testlab-ca.pem",
+				KeyTLSCertFile:   "/etc/ssl/client.pem",
+				KeyTLSKeyFile:    "/etc/ssl/client-key.pem",
+				KeyTLSMinVersion: "1.3",
+			},
+			want: Config{
+				Domain:   "testlab",
+				AuthType: AuthTypeBasic,
+				Timeout:  time.Minute,
+				UserName: "test@testlab.com",
+				APIToken: "gkdsaj)({jgo43646435#$!ga",
+				ProxyURL: "https://proxy.testlab.com:3128",
+				TLS: TLSConfig{
+					CAFile:     "/etc/ssl/testlab-ca.pem",
+					CertFile:   "/etc/ssl/client.pem",
+					KeyFile:    "/etc/ssl/client-key.pem",
+					MinVersion: tls.VersionTLS13,
+				},
+			},
+		},
+		{
+			name: "Login with negative timeout",
+			config: map[string]string{
+				KeyDomain:   "testlab",
+				KeyUserName: "test@testlab.com",
+				KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+				KeyTimeout:  "-5s",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.timeout\" config value should be a positive duration, got \"-5s\""),
+		},
+		{
+			name: "Login with unsupported proxy scheme",
+			config: map[string]string{
+				KeyDomain:   "testlab",
+				KeyUserName: "test@testlab.com",
+				KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+				KeyProxyURL: "ftp://proxy.testlab.com",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.proxyURL\" config value should be an absolute http, https or socks5 url, got \"ftp://proxy.testlab.com\""),
+		},
+		{
+			name: "Login with client certificate without key",
+			config: map[string]string{
+				KeyDomain:      "testlab",
+				KeyUserName:    "test@testlab.com",
+				KeyAPIToken:    "gkdsaj)({jgo43646435#$!ga",
+				KeyTLSCertFile: "/etc/ssl/client.pem",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.tls.keyFile\" config value must be set"),
+		},
+		{
+			name: "Login with unsupported TLS version",
+			config: map[string]string{
+				KeyDomain:        "testlab",
+				KeyUserName:      "test@testlab.com",
+				KeyAPIToken:      "gkdsaj)({jgo43646435#$!ga",
+				KeyTLSMinVersion: "1.4",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.tls.minVersion\" config value should be one of \"1.0\", \"1.1\", \"1.2\" or \"1.3\", got \"1.4\""),
+		},
+		{
+			name: "Login with APIToken referencing an environment variable",
+			config: map[string]string{
+				KeyDomain:   "testlab",
+				KeyUserName: "test@testlab.com",
+				KeyAPIToken: "${env:ZENDESK_MISSING_TOKEN}",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.apiToken\" config value: environment variable \"ZENDESK_MISSING_TOKEN\" is not set"),
+		},
+		{
+			name: "Login with OAuth client secret referencing a missing file",
+			config: map[string]string{
+				KeyDomain:            "testlab",
+				KeyAuthType:          AuthTypeOAuth,
+				KeyOAuthClientID:     "dummy_client",
+				KeyOAuthClientSecret: "${file:/var/run/secrets/zendesk/missing}",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.oauth.clientSecret\" config value: could not read the secret file: stat /var/run/secrets/zendesk/missing: no such file or directory"),
+		},
+		{
+			name: "Login with OAuth refresh token not referencing a file",
+			config: map[string]string{
+				KeyDomain:            "testlab",
+				KeyAuthType:          AuthTypeOAuth,
+				KeyOAuthClientID:     "dummy_client",
+				KeyOAuthClientSecret: "dummy_secret",
+				KeyOAuthRefreshToken: "dummy_refresh_token",
+			},
+			want:    Config{},
+			isError: true,
+			err:     fmt.Errorf("\"zendesk.oauth.refreshToken\" config value must reference a writable file, i.e. ${file:/path/to/refresh-token}, as zendesk rotates the refresh token"),
+		},
 		{
 			name:    "Login without domain, username and APIToken",
 			config:  map[string]string{},
@@ -122,3 +349,8 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_URL(t *testing.T) {
+	assert.Equal(t, "https://testlab.zendesk.com", Config{Domain: "testlab"}.URL())
+	assert.Equal(t, "http://localhost:8080", Config{Domain: "testlab", BaseURL: "http://localhost:8080"}.URL())
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SecretFilePrefix starts the reference of a secret read from a file, i.e. `${file:/var/run/secrets/zendesk/api-token}`
+	// for a mounted kubernetes secret
+	SecretFilePrefix = "${file:"
+	// SecretEnvPrefix starts the reference of a secret read from an environment variable, i.e. `${env:ZENDESK_API_TOKEN}`
+	SecretEnvPrefix = "${env:"
+	// SecretSuffix ends the references of the secrets
+	SecretSuffix = "}"
+	// SecretEscape escapes the inline secrets which would otherwise be read as references, i.e. `$${env:abc}` is the secret `${env:abc}`
+	SecretEscape = "$"
+)
+
+// secretFiles caches the content of the secret files, till their modification time or size changes
+var secretFiles = struct {
+	sync.Mutex
+	files map[string]secretFile
+}{files: make(map[string]secretFile)}
+
+type secretFile struct {
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+// ResolveSecret returns the value of a credential config value, which is either the secret itself,
+// a `${file:<path>}` or an `${env:<variable>}` reference to it. Files are read again once they change,
+// so the callers resolving the secret on every use pick up rotated secrets.
+// Surrounding whitespace is trimmed from referenced secrets. Other values are inline secrets, used as is,
+// once the escape is removed from the inline secrets shaped like references.
+func ResolveSecret(value string) (string, error) {
+	if path, ok := secretReference(value, SecretFilePrefix); ok {
+		return readSecretFile(path)
+	}
+	if name, ok := secretReference(value, SecretEnvPrefix); ok {
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q is not set", name)
+		}
+		return strings.TrimSpace(secret), nil
+	}
+	if escapedSecret(value) {
+		return strings.TrimPrefix(value, SecretEscape), nil
+	}
+	return value, nil
+}
+
+// escapedSecret reports whether the value is a reference, or an escaped reference, preceded by the escape
+func escapedSecret(value string) bool {
+	if !strings.HasPrefix(value, SecretEscape) {
+		return false
+	}
+	value = strings.TrimPrefix(value, SecretEscape)
+	_, file := secretReference(value, SecretFilePrefix)
+	_, env := secretReference(value, SecretEnvPrefix)
+	return file || env || escapedSecret(value)
+}
+
+// secretReference returns the target of the reference starting with the prefix, false if the value isn't one
+func secretReference(value, prefix string) (string, bool) {
+	if !strings.HasPrefix(value, prefix) || !strings.HasSuffix(value, SecretSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(value, prefix), SecretSuffix), true
+}
+
+func readSecretFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read the secret file: %w", err)
+	}
+
+	secretFiles.Lock()
+	defer secretFiles.Unlock()
+	cached, ok := secretFiles.files[path]
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.value, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read the secret file: %w", err)
+	}
+	value := strings.TrimSpace(string(content))
+	secretFiles.files[path] = secretFile{modTime: info.ModTime(), size: info.Size(), value: value}
+	return value, nil
+}
+
+// WriteSecret replaces the secret referenced by the credential config value, which must reference a file.
+// The file is replaced at once, so it holds either the previous or the new secret if the connector stops meanwhile.
+func WriteSecret(value, secret string) error {
+	path, ok := secretReference(value, SecretFilePrefix)
+	if !ok {
+		return fmt.Errorf("the secret can only be written to a %q reference", SecretFilePrefix+"<path>"+SecretSuffix)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("could not write the secret file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("could not write the secret file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(secret)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write the secret file: %w", err)
+	}
+
+	// the cache is updated right away, the modification time may not change within the resolution of the file system
+	info, err = os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("could not write the secret file: %w", err)
+	}
+	secretFiles.Lock()
+	defer secretFiles.Unlock()
+	secretFiles.files[path] = secretFile{modTime: info.ModTime(), size: info.Size(), value: secret}
+	return nil
+}
+
+// validateSecrets checks the referenced secrets can be resolved and are not empty
+func validateSecrets(cfg map[string]string, keys ...string) error {
+	for _, key := range keys {
+		if cfg[key] == "" {
+			continue
+		}
+		secret, err := ResolveSecret(cfg[key])
+		if err != nil {
+			return fmt.Errorf("%q config value: %w", key, err)
+		}
+		if secret == "" {
+			return fmt.Errorf("%q config value references an empty secret", key)
+		}
+	}
+	return nil
+}
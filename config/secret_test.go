@@ -0,0 +1,100 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("ZENDESK_TEST_TOKEN", "env_token\n")
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("file_token\n"), 0o600))
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   string
+	}{
+		{name: "inline secret", value: "gkdsaj)({jgo43646435#$!ga", want: "gkdsaj)({jgo43646435#$!ga"},
+		{name: "environment variable", value: "${env:ZENDESK_TEST_TOKEN}", want: "env_token"},
+		{name: "missing environment variable", value: "${env:ZENDESK_MISSING_TOKEN}", err: "environment variable \"ZENDESK_MISSING_TOKEN\" is not set"},
+		{name: "file", value: "${file:" + path + "}", want: "file_token"},
+		{name: "missing file", value: "${file:" + path + ".missing}", err: "could not read the secret file"},
+		// the inline secrets looking like the former references are kept as is
+		{name: "inline env prefix", value: "env:ZENDESK_TEST_TOKEN", want: "env:ZENDESK_TEST_TOKEN"},
+		{name: "inline file prefix", value: "file://" + path, want: "file://" + path},
+		{name: "unterminated reference", value: "${env:ZENDESK_TEST_TOKEN", want: "${env:ZENDESK_TEST_TOKEN"},
+		{name: "escaped reference", value: "$${env:ZENDESK_TEST_TOKEN}", want: "${env:ZENDESK_TEST_TOKEN}"},
+		{name: "escaped escape", value: "$$${file:" + path + "}", want: "$${file:" + path + "}"},
+		{name: "inline escape", value: "$${abc}", want: "$${abc}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecret(tt.value)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolveSecret_FileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("first_token"), 0o600))
+
+	got, err := ResolveSecret("${file:" + path + "}")
+	assert.NoError(t, err)
+	assert.Equal(t, "first_token", got)
+
+	// the rotated secret is read once the file changes
+	assert.NoError(t, os.WriteFile(path, []byte("second_token"), 0o600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	got, err = ResolveSecret("${file:" + path + "}")
+	assert.NoError(t, err)
+	assert.Equal(t, "second_token", got)
+}
+
+func TestWriteSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("first_token"), 0o640))
+	got, err := ResolveSecret("${file:" + path + "}")
+	assert.NoError(t, err)
+	assert.Equal(t, "first_token", got)
+
+	// the secret is resolved right away, even if the file keeps the same size and modification time
+	assert.NoError(t, WriteSecret("${file:"+path+"}", "other_token"))
+	got, err = ResolveSecret("${file:" + path + "}")
+	assert.NoError(t, err)
+	assert.Equal(t, "other_token", got)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	assert.ErrorContains(t, WriteSecret("${env:ZENDESK_TEST_TOKEN}", "other_token"), "the secret can only be written to a \"${file:<path>}\" reference")
+	assert.ErrorContains(t, WriteSecret("${file:"+path+".missing}", "other_token"), "could not write the secret file")
+}
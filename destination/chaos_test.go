@@ -0,0 +1,148 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk/zendesktest"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDestination_Faults writes tickets while zendesk misbehaves, and once the write fails, delivers the records
+// which aren't acked successfully again to a restarted destination. It asserts every ticket is written once,
+// and every record is acked successfully once, after being nacked if its write failed.
+func TestDestination_Faults(t *testing.T) {
+	tests := []struct {
+		name      string
+		inject    func(s *zendesktest.Server)
+		writeFail bool
+	}{
+		{
+			name: "server errors on import",
+			inject: func(s *zendesktest.Server) {
+				s.Inject(zendesktest.Fault{Kind: zendesktest.FaultServerError, Path: zendesktest.CreateManyPath, StatusCode: 503, Times: 3})
+			},
+			writeFail: true,
+		},
+		{
+			name: "slow response beyond the timeout",
+			inject: func(s *zendesktest.Server) {
+				// the first batch is imported, though its request timed out
+				s.Inject(zendesktest.Fault{Kind: zendesktest.FaultSlowResponse, Path: zendesktest.CreateManyPath, Delay: 300 * time.Millisecond, Times: 2})
+			},
+			writeFail: true,
+		},
+		{
+			name: "truncated response",
+			inject: func(s *zendesktest.Server) {
+				s.Inject(zendesktest.Fault{Kind: zendesktest.FaultTruncatedJSON, Path: zendesktest.CreateManyPath, Times: 2})
+			},
+		},
+		{
+			name: "rate limited",
+			inject: func(s *zendesktest.Server) {
+				s.RateLimit(1, time.Second)
+			},
+		},
+		{
+			name: "server error storm",
+			inject: func(s *zendesktest.Server) {
+				s.Inject(zendesktest.Fault{Kind: zendesktest.FaultServerError})
+			},
+			writeFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := zendesktest.NewServer()
+			defer server.Close()
+			tt.inject(server)
+
+			var mux sync.Mutex
+			acks := make(map[string][]error)
+			// write delivers the records which aren't acked successfully yet to a new destination, till the write fails
+			write := func() error {
+				d := &Destination{
+					mux: &sync.Mutex{},
+					cfg: Config{BufferSize: 10, MaxRetries: 3},
+					writer: zendesk.NewBulkImporter(
+						zendesk.NewClient(server.URL, zendesk.Retry(3, time.Millisecond), zendesk.Timeout(100*time.Millisecond)),
+						3,
+					),
+				}
+				var err error
+				for i := 0; i < 25 && err == nil; i++ {
+					description := fmt.Sprintf("ticket %d", i)
+					mux.Lock()
+					errs := acks[description]
+					mux.Unlock()
+					if len(errs) > 0 && errs[len(errs)-1] == nil {
+						continue
+					}
+					record := sdk.Record{
+						Position: sdk.Position(fmt.Sprint(i)),
+						Payload:  sdk.RawData(fmt.Sprintf(`{"external_id":"%d","description":%q}`, i, description)),
+					}
+					err = d.WriteAsync(context.Background(), record, func(err error) error {
+						mux.Lock()
+						defer mux.Unlock()
+						acks[description] = append(acks[description], err)
+						return nil
+					})
+				}
+				if err == nil {
+					err = d.Flush(context.Background())
+				}
+				_ = d.Teardown(context.Background())
+				return err
+			}
+
+			err := write()
+			if tt.writeFail {
+				assert.Error(t, err)
+				// the destination restarts once zendesk recovers
+				server.WaitIdle() // zendesk still handles the requests the client gave up on
+				server.ClearFaults()
+				err = write()
+			}
+			assert.NoError(t, err)
+
+			written := make(map[string]int)
+			for _, ticket := range server.Tickets() {
+				written[fmt.Sprint(ticket["description"])]++
+			}
+			assert.Len(t, written, 25)
+			assert.Len(t, acks, 25)
+			for description, count := range written {
+				assert.Equal(t, 1, count, "ticket %q written more than once", description)
+				errs := acks[description]
+				for _, err := range errs[:len(errs)-1] {
+					assert.Error(t, err, description)
+				}
+				assert.NoError(t, errs[len(errs)-1], description)
+			}
+		})
+	}
+}
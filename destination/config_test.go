@@ -18,6 +18,7 @@ package destination
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/conduitio/conduit-connector-zendesk/config"
 	"github.com/stretchr/testify/assert"
@@ -45,6 +46,8 @@ func TestParse_Destination(t *testing.T) {
 				MaxRetries: 5,
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -65,6 +68,8 @@ func TestParse_Destination(t *testing.T) {
 				BufferSize: 100,
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -84,6 +89,8 @@ func TestParse_Destination(t *testing.T) {
 				MaxRetries: 3,
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -104,6 +111,8 @@ func TestParse_Destination(t *testing.T) {
 				MaxRetries: 3,
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -124,6 +133,8 @@ func TestParse_Destination(t *testing.T) {
 				MaxRetries: 3,
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -59,7 +59,11 @@ func (d *Destination) Configure(ctx context.Context, cfg map[string]string) erro
 func (d *Destination) Open(ctx context.Context) error {
 	d.buffer = make([]sdk.Record, 0, d.cfg.BufferSize)
 	d.ackFuncCache = make([]sdk.AckFunc, 0, d.cfg.BufferSize)
-	d.writer = zendesk.NewBulkImporter(d.cfg.UserName, d.cfg.APIToken, d.cfg.Domain, d.cfg.MaxRetries)
+	client, err := zendesk.NewAccountClient(d.cfg.Config)
+	if err != nil {
+		return err
+	}
+	d.writer = zendesk.NewBulkImporter(client, d.cfg.MaxRetries)
 	return nil
 }
 
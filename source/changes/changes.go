@@ -0,0 +1,149 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// Detector detects the records whose tracked fields changed since the last record emitted for the same object,
+// by hashing the tracked fields of the record payloads. The hashes of the emitted records are committed to the local store
+// once their position is acknowledged, so the records not processed before a restart are emitted again.
+type Detector struct {
+	fields [][]string // paths of the tracked fields
+	store  *store
+
+	mux     sync.Mutex
+	hashes  map[string]string // hashes of the records emitted, committed or not, keyed by entity key
+	pending []pendingHash     // hashes of the emitted records not acknowledged yet, in the order they were emitted
+}
+
+type pendingHash struct {
+	position string
+	entity   string
+	key      string
+	hash     string
+}
+
+// New returns the detector of the changes of the fields, nested fields are separated with dots,
+// the hashes are stored in the file at the path
+func New(fields []string, path string) (*Detector, error) {
+	s, err := openStore(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Detector{store: s, hashes: make(map[string]string)}
+	for _, field := range fields {
+		d.fields = append(d.fields, strings.Split(field, "."))
+	}
+	return d, nil
+}
+
+// Changed reports whether the hash of the tracked fields of the object of the entity changed since the last record emitted
+// for the same key, and records the hash as emitted by the record at the position if it did. New objects are changed.
+func (d *Detector) Changed(entity, key, hash string, pos sdk.Position) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	last, ok := d.hashes[storeKey(entity, key)]
+	if !ok {
+		last, ok = d.store.get(entity, key)
+	}
+	if ok && last == hash {
+		return false
+	}
+	d.hashes[storeKey(entity, key)] = hash
+	d.pending = append(d.pending, pendingHash{position: string(pos), entity: entity, key: key, hash: hash})
+	return true
+}
+
+// Ack commits the hashes of the records emitted till the record at the position, to the store
+func (d *Detector) Ack(pos sdk.Position) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	acked := -1
+	for i, p := range d.pending {
+		if p.position == string(pos) {
+			acked = i
+			break
+		}
+	}
+	if acked < 0 {
+		return nil
+	}
+	for _, p := range d.pending[:acked+1] {
+		if err := d.store.put(p.entity, p.key, p.hash); err != nil {
+			return fmt.Errorf("could not store the hash of %s %s: %w", p.entity, p.key, err)
+		}
+	}
+	d.pending = d.pending[acked+1:]
+	if len(d.pending) == 0 {
+		// the uncommitted hashes are all committed, only the store is needed
+		d.hashes = make(map[string]string)
+	}
+	return d.store.flush()
+}
+
+// Close writes the committed hashes and closes the store
+func (d *Detector) Close() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.store.close()
+}
+
+// Hash returns the hash of the tracked fields of the JSON payload, missing fields are null
+func (d *Detector) Hash(payload []byte) (string, error) {
+	var object map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return "", fmt.Errorf("could not unmarshal the payload: %w", err)
+	}
+
+	values := make([]interface{}, len(d.fields))
+	for i, path := range d.fields {
+		values[i] = lookup(object, path)
+	}
+	// maps are marshaled with sorted keys, so the hash doesn't depend on the field order of the payload
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal the tracked fields: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// lookup returns the value at the path of the object, nil if missing
+func lookup(object map[string]interface{}, path []string) interface{} {
+	var value interface{} = object
+	for _, field := range path {
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = child[field]
+	}
+	return value
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector_Changed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.log")
+	d, err := New([]string{"status", "via.channel"}, path)
+	assert.NoError(t, err)
+
+	changed := func(d *Detector, entity, pos, key, payload string) bool {
+		t.Helper()
+		hash, err := d.Hash([]byte(payload))
+		assert.NoError(t, err)
+		return d.Changed(entity, key, hash, sdk.Position(pos))
+	}
+
+	// new objects are changed, untracked fields are ignored
+	assert.True(t, changed(d, "tickets", "1", "12", `{"status":"open","via":{"channel":"web"},"metric":1}`))
+	assert.False(t, changed(d, "tickets", "2", "12", `{"via":{"channel":"web"},"status":"open","metric":2}`))
+	assert.True(t, changed(d, "tickets", "3", "12", `{"status":"solved","via":{"channel":"web"}}`))
+	assert.True(t, changed(d, "tickets", "4", "13", `{"status":"open"}`))
+	// the same key of another entity is another object
+	assert.True(t, changed(d, "users", "5", "12", `{"status":"solved","via":{"channel":"web"}}`))
+	_, err = d.Hash([]byte(`{"status":`))
+	assert.Error(t, err)
+
+	// only the hashes of the acknowledged records are stored
+	assert.NoError(t, d.Ack(sdk.Position("3")))
+	assert.NoError(t, d.Close())
+
+	d, err = New([]string{"status", "via.channel"}, path)
+	assert.NoError(t, err)
+	defer d.Close()
+	assert.False(t, changed(d, "tickets", "6", "12", `{"status":"solved","via":{"channel":"web"}}`))
+	assert.True(t, changed(d, "tickets", "7", "13", `{"status":"open"}`))
+}
+
+func TestOpenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.log")
+	// the last line of a key wins, a line truncated by a crash is ignored
+	err := os.WriteFile(path, []byte("tickets\t12\taaaa\ntickets\t12\tbbbb\ntickets\t13\tcccc\ntickets\t14"), 0o600)
+	assert.NoError(t, err)
+
+	s, err := openStore(path)
+	assert.NoError(t, err)
+	hash, ok := s.get("tickets", "12")
+	assert.True(t, ok)
+	assert.Equal(t, "bbbb", hash)
+	_, ok = s.get("tickets", "14")
+	assert.False(t, ok)
+
+	// the lines appended after the truncated line are read
+	assert.NoError(t, s.put("tickets", "15", "dddd"))
+	assert.NoError(t, s.close())
+	s, err = openStore(path)
+	assert.NoError(t, err)
+	defer s.close()
+	hash, ok = s.get("tickets", "15")
+	assert.True(t, ok)
+	assert.Equal(t, "dddd", hash)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// compactionThreshold is the number of stale lines from which the log is compacted when opened
+const compactionThreshold = 10000
+
+// store is the local store of the hashes, an append-only log of `entity key hash` lines, the last line of a key wins.
+// The log is compacted when opened, once it holds enough stale lines.
+type store struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	hashes map[string]string // hash of each entity key
+
+	truncated bool // the last line of the log was truncated by a crash
+}
+
+// openStore loads the hashes of the log at the path, creating it if missing
+func openStore(path string) (*store, error) {
+	s := &store{path: path, hashes: make(map[string]string)}
+	lines, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if lines-len(s.hashes) >= compactionThreshold {
+		if err := s.compact(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the change store: %w", err)
+	}
+	s.writer = bufio.NewWriter(s.file)
+	if s.truncated {
+		// end the truncated line, so it doesn't corrupt the next one
+		if _, err := s.writer.WriteString("\n"); err != nil {
+			s.file.Close()
+			return nil, fmt.Errorf("could not write the change store: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// load reads the log, returning the number of lines read
+func (s *store) load() (int, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not open the change store: %w", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			// line truncated by a crash, the hash is computed again
+			continue
+		}
+		s.hashes[storeKey(fields[0], fields[1])] = fields[2]
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("could not read the change store: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("could not read the change store: %w", err)
+	}
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+			return 0, fmt.Errorf("could not read the change store: %w", err)
+		}
+		s.truncated = last[0] != '\n'
+	}
+	return lines, nil
+}
+
+// compact rewrites the log with the last hash of every key, replacing the log once written
+func (s *store) compact() error {
+	tmp := s.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not compact the change store: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	for key, hash := range s.hashes {
+		entity, id := splitStoreKey(key)
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", entity, id, hash)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("could not compact the change store: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not compact the change store: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *store) get(entity, key string) (string, bool) {
+	hash, ok := s.hashes[storeKey(entity, key)]
+	return hash, ok
+}
+
+// put appends the hash of the key to the log, it is written once flushed
+func (s *store) put(entity, key, hash string) error {
+	s.hashes[storeKey(entity, key)] = hash
+	_, err := fmt.Fprintf(s.writer, "%s\t%s\t%s\n", entity, key, hash)
+	return err
+}
+
+func (s *store) flush() error {
+	return s.writer.Flush()
+}
+
+func (s *store) close() error {
+	if err := s.writer.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+func storeKey(entity, key string) string {
+	return entity + "\t" + key
+}
+
+func splitStoreKey(key string) (string, string) {
+	entity, id, _ := strings.Cut(key, "\t")
+	return entity, id
+}
@@ -18,30 +18,183 @@ package source
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/conduitio/conduit-connector-zendesk/source/filter"
+	"github.com/conduitio/conduit-connector-zendesk/source/iterator"
+	"github.com/conduitio/conduit-connector-zendesk/source/projection"
+	"github.com/conduitio/conduit-connector-zendesk/source/webhook"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
 )
 
 const (
 	KeyPollingPeriod = "pollingPeriod"
 
+	// KeyEntities is the comma separated list of zendesk entities to be read by the source, tickets are read by default
+	KeyEntities = "entities"
+
+	// KeySnapshot determines whether a fresh pipeline starts with a snapshot of the existing objects,
+	// before switching to CDC mode. Snapshot is disabled by default, fresh pipelines read the entities as before.
+	KeySnapshot = "snapshot"
+
+	// KeyFilter is the expression the objects read by the source must match, i.e. `brand_id in (1, 2) and status != "closed"`,
+	// every object is read if empty
+	KeyFilter = "filter"
+
+	// KeyFieldsInclude is the comma separated list of the fields kept in the payloads, every field is kept if empty.
+	// Nested fields are separated with dots, i.e. `via.source.from.address`.
+	KeyFieldsInclude = "fields.include"
+	// KeyFieldsExclude is the comma separated list of the fields removed from the payloads
+	KeyFieldsExclude = "fields.exclude"
+	// KeyFieldsRedact is the comma separated list of `field:action` redaction rules, the action is one of drop, hash or mask
+	KeyFieldsRedact = "fields.redact"
+	// KeyFieldsHashSalt is the salt of the hash redaction rules, it may reference a secret
+	KeyFieldsHashSalt = "fields.hashSalt"
+
+	// KeyBackfillStart enables the backfill of the entities without position, instead of the snapshot,
+	// reading the objects updated from the RFC3339 start time in parallel time slices
+	KeyBackfillStart = "backfill.start"
+	// KeyBackfillEnd is the RFC3339 time till which the objects are backfilled, the time the source is opened by default.
+	// The entities are read in CDC mode from the end time, once backfilled.
+	KeyBackfillEnd = "backfill.end"
+	// KeyBackfillSlices is the number of time slices the backfill is split into
+	KeyBackfillSlices = "backfill.slices"
+	// KeyBackfillConcurrency is the number of slices read in parallel
+	KeyBackfillConcurrency = "backfill.concurrency"
+
+	// KeyStartTime is the RFC3339 time from which the objects of the entities without position are read,
+	// the entities are read from the beginning by default
+	KeyStartTime = "startTime"
+	// KeyStartFrom is the time from which the objects of the entities without position are read, either a RFC3339 time,
+	// a negative duration relative to the time the source is opened, i.e. `-720h`, or `now`
+	KeyStartFrom = "startFrom"
+	// KeyEndTime bounds the export to the objects updated before the RFC3339 end time,
+	// the source stops reading once every entity reaches it. The source reads without end by default.
+	KeyEndTime = "endTime"
+
+	// KeySearchQuery puts the source in search mode, reading the objects matching the zendesk search query,
+	// i.e. `tags:vip`, with the search export api, instead of the entities
+	KeySearchQuery = "search.query"
+	// KeySearchType is the type of the objects searched, one of ticket, user, organization or group
+	KeySearchType = "search.type"
+
+	// KeyWebhookAddress enables the webhook mode, running a server on the address, i.e. `:8080`,
+	// which receives the objects posted by zendesk webhooks, along with the incremental exports reconciling the missed ones
+	KeyWebhookAddress = "webhook.address"
+	// KeyWebhookPath is the path prefix of the webhook routes, the objects of an entity are posted to `<path>/<entity>`
+	KeyWebhookPath = "webhook.path"
+	// KeyWebhookSecret is the signing secret of the webhook, used to verify the requests. It may reference a secret.
+	KeyWebhookSecret = "webhook.secret"
+	// KeyWebhookObjectField is the dotted path of the object in the webhook request body, i.e. `ticket`, the body is the object if empty
+	KeyWebhookObjectField = "webhook.objectField"
+	// KeyWebhookTolerance is the maximum age of the signature timestamp of the webhook requests
+	KeyWebhookTolerance = "webhook.tolerance"
+	// KeyWebhookSweepPeriod is the period of the incremental exports in webhook mode, replacing the polling period
+	KeyWebhookSweepPeriod = "webhook.sweepPeriod"
+
+	// KeyDedupWindow is the number of the last emitted objects remembered in the position of each entity, records of the objects
+	// already emitted with the same update time are dropped, and the entities resume from the last modified time, instead of the next second.
+	// The deduplication is disabled by default.
+	KeyDedupWindow = "dedup.window"
+
+	// KeyChangesFields is the comma separated list of the tracked fields, records are only emitted when one of them changed
+	// since the last record emitted for the same object. Nested fields are separated with dots. Every record is emitted if empty.
+	KeyChangesFields = "changes.fields"
+	// KeyChangesStorePath is the path of the local file storing the hashes of the tracked fields, required with the tracked fields
+	KeyChangesStorePath = "changes.storePath"
+
+	// KeySchemaInfer enables the inference of the schema of the tickets from the ticket fields of the account,
+	// its fingerprint is set in the metadata of the tickets, and a schema change record is emitted whenever it changes
+	KeySchemaInfer = "schema.infer"
+	// KeyTicketFieldsRefreshPeriod is the period at which the ticket fields are fetched again, with the schema inference or flattening
+	KeyTicketFieldsRefreshPeriod = "ticketFields.refreshPeriod"
+	// KeyCustomFieldsFlatten rewrites the `custom_fields` array of the tickets into an object keyed by field title or alias,
+	// with the values coerced to the type of their field
+	KeyCustomFieldsFlatten = "customFields.flatten"
+	// KeyCustomFieldsAliases is the comma separated list of `id:alias` keys of the flattened custom fields, replacing their title
+	KeyCustomFieldsAliases = "customFields.aliases"
+
+	// KeyAccounts is the comma separated list of the subdomains of the accounts read along with each other, instead of the domain.
+	// The zendesk configs are shared by the accounts, except the domain and base url, and can be set for an account
+	// with `accounts.<subdomain>.<config>`, i.e. `accounts.acme-eu.apiToken`.
+	KeyAccounts = "accounts"
+
 	// KeyPollingPeriod determines polling time from config, if it empty or if config not provided.
 	// then the defaultPollingPeriod taken as 2 minutes.
 	defaultPollingPeriod = "6s"
+
+	defaultEntities = zendesk.EntityTickets
+
+	defaultSnapshot = "false"
+
+	// startFromNow is the start from value starting the export at the time the source is opened
+	startFromNow = "now"
+
+	defaultSearchType = "ticket"
+
+	defaultWebhookPath        = "/"
+	defaultWebhookTolerance   = "5m"
+	defaultWebhookSweepPeriod = "15m"
+
+	defaultSchemaInfer               = "false"
+	defaultTicketFieldsRefreshPeriod = "15m"
+	defaultCustomFieldsFlatten       = "false"
+
+	// zendeskKeyPrefix is the prefix of the zendesk configs, omitted from the configs of the accounts
+	zendeskKeyPrefix = "zendesk."
+
+	defaultBackfillSlices      = 8
+	defaultBackfillConcurrency = 4
 )
 
+// subdomainPattern matches the subdomains of the accounts
+var subdomainPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
 type Config struct {
-	config.Config
-	PollingPeriod time.Duration // time interval for next zendesk api hit
+	config.Config                          // config of the account, the first account when reading multiple accounts
+	PollingPeriod   time.Duration          // time interval for next zendesk api hit
+	Entities        []string               // zendesk entities to be read
+	Snapshot        bool                   // read the existing objects as snapshot, before switching to CDC
+	Filter          *filter.Filter         // objects not matching the filter are dropped, nil to read every object
+	Projection      *projection.Projection // fields selection and redaction of the payloads, nil to keep the payloads as is
+	Backfill        *iterator.Backfill     // parallel backfill of the entities without position, nil to use the snapshot, ending when the source is opened if its end is zero
+	StartTime       time.Time              // start time of the entities without position, from startTime or startFrom, zero to read them from the beginning
+	StartOffset     *time.Duration         // start time of the entities without position relative to the time the source is opened, from startFrom, nil if not relative
+	EndTime         time.Time              // objects updated from the end time on aren't read, zero to read without end
+	SearchQuery     string                 // search query of the objects read in search mode, empty to read the entities
+	SearchType      string                 // type of the objects searched
+	Webhook         *webhook.Config        // webhook receiving the objects pushed by zendesk, nil to only poll the exports
+	DedupWindow     int                    // number of the last emitted objects remembered to drop duplicates, zero to disable it
+	ChangeFields    []string               // fields tracked to only emit the records of changed objects, empty to emit every record
+	ChangeStorePath string                 // path of the local store of the hashes of the tracked fields
+	InferSchema     bool                   // infer the schema of the tickets, and emit its changes
+	FlattenFields   bool                   // flatten the custom fields of the tickets into an object keyed by field
+	FieldAliases    map[float64]string     // keys of the flattened custom fields by id, replacing their title
+	// TicketFieldsRefreshPeriod is the period at which the ticket fields are fetched again, zero if they aren't fetched
+	TicketFieldsRefreshPeriod time.Duration
+	// Accounts are the configs of the accounts read along with each other, empty to only read the account of the config
+	Accounts []config.Config
 }
 
 // Parse validate zendesk config and pollingPeriod
 func Parse(cfg map[string]string) (Config, error) {
-	defaultConfig, err := config.Parse(cfg)
+	accounts, err := parseAccounts(cfg)
 	if err != nil {
 		return Config{}, err
 	}
+	var defaultConfig config.Config
+	if len(accounts) > 0 {
+		defaultConfig = accounts[0]
+	} else {
+		defaultConfig, err = config.Parse(cfg)
+		if err != nil {
+			return Config{}, err
+		}
+	}
 
 	pollingPeriod := cfg[KeyPollingPeriod]
 	if pollingPeriod == "" {
@@ -52,9 +205,500 @@ func Parse(cfg map[string]string) (Config, error) {
 		return Config{}, fmt.Errorf("%q can't parse time interval: %w", pollingPeriod, err)
 	}
 
+	entities, err := parseEntities(cfg[KeyEntities])
+	if err != nil {
+		return Config{}, err
+	}
+
+	searchQuery, searchType, err := parseSearch(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	if searchQuery != "" {
+		entities = []string{zendesk.EntitySearch}
+	}
+
+	snapshotString := cfg[KeySnapshot]
+	if snapshotString == "" {
+		snapshotString = defaultSnapshot
+	}
+	snapshot, err := strconv.ParseBool(snapshotString)
+	if err != nil {
+		return Config{}, fmt.Errorf("%q config value should be a boolean: %w", KeySnapshot, err)
+	}
+
+	var objectFilter *filter.Filter
+	if expression := strings.TrimSpace(cfg[KeyFilter]); expression != "" {
+		objectFilter, err = filter.Parse(expression)
+		if err != nil {
+			return Config{}, fmt.Errorf("%q config value is not a valid filter: %w", KeyFilter, err)
+		}
+	}
+
+	fieldsProjection, err := parseProjection(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+
+	backfill, err := parseBackfill(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+
+	startTime, startOffset, endTime, err := parseWindow(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	if (!startTime.IsZero() || startOffset != nil) && backfill != nil {
+		return Config{}, fmt.Errorf("%q and %q can't be set together", startKey(cfg), KeyBackfillStart)
+	}
+
+	webhookConfig, err := parseWebhook(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	if webhookConfig != nil && searchQuery != "" {
+		return Config{}, fmt.Errorf("%q and %q can't be set together", KeyWebhookAddress, KeySearchQuery)
+	}
+	if webhookConfig != nil && !endTime.IsZero() {
+		return Config{}, fmt.Errorf("%q and %q can't be set together", KeyWebhookAddress, KeyEndTime)
+	}
+
+	dedupWindow := 0
+	if cfg[KeyDedupWindow] != "" {
+		dedupWindow, err = strconv.Atoi(cfg[KeyDedupWindow])
+		if err != nil || dedupWindow < 0 {
+			return Config{}, fmt.Errorf("%q config value should be a non-negative integer, got %q", KeyDedupWindow, cfg[KeyDedupWindow])
+		}
+	}
+	// search results are positioned at the time their run started from, not at their update time
+	if dedupWindow > 0 && searchQuery != "" {
+		return Config{}, fmt.Errorf("%q and %q can't be set together", KeyDedupWindow, KeySearchQuery)
+	}
+
+	changeFields := splitList(cfg[KeyChangesFields])
+	if len(changeFields) > 0 && cfg[KeyChangesStorePath] == "" {
+		return Config{}, fmt.Errorf("%q config value must be set to track %q", KeyChangesStorePath, KeyChangesFields)
+	}
+
+	inferSchema, err := parseTicketsOption(cfg, KeySchemaInfer, defaultSchemaInfer, entities, searchType)
+	if err != nil {
+		return Config{}, err
+	}
+	flattenFields, err := parseTicketsOption(cfg, KeyCustomFieldsFlatten, defaultCustomFieldsFlatten, entities, searchType)
+	if err != nil {
+		return Config{}, err
+	}
+	fieldAliases, err := parseAliases(cfg[KeyCustomFieldsAliases])
+	if err != nil {
+		return Config{}, err
+	}
+	if len(fieldAliases) > 0 && !flattenFields {
+		return Config{}, fmt.Errorf("%q config value must be true to set %q", KeyCustomFieldsFlatten, KeyCustomFieldsAliases)
+	}
+	// the ticket fields differ between the accounts
+	if len(accounts) > 0 && inferSchema {
+		return Config{}, fmt.Errorf("%q and %q can't be set together", KeyAccounts, KeySchemaInfer)
+	}
+	if len(accounts) > 0 && flattenFields {
+		return Config{}, fmt.Errorf("%q and %q can't be set together", KeyAccounts, KeyCustomFieldsFlatten)
+	}
+	// the webhook requests don't tell the account they come from
+	if len(accounts) > 0 && webhookConfig != nil {
+		return Config{}, fmt.Errorf("%q and %q can't be set together", KeyAccounts, KeyWebhookAddress)
+	}
+
+	var refreshPeriod time.Duration
+	if inferSchema || flattenFields {
+		refreshPeriod, err = parseDuration(cfg, KeyTicketFieldsRefreshPeriod, defaultTicketFieldsRefreshPeriod)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
 	sourceConfig := Config{
-		Config:        defaultConfig,
-		PollingPeriod: duration,
+		Config:                    defaultConfig,
+		PollingPeriod:             duration,
+		Entities:                  entities,
+		Snapshot:                  snapshot,
+		Filter:                    objectFilter,
+		Projection:                fieldsProjection,
+		Backfill:                  backfill,
+		StartTime:                 startTime,
+		StartOffset:               startOffset,
+		EndTime:                   endTime,
+		SearchQuery:               searchQuery,
+		SearchType:                searchType,
+		Webhook:                   webhookConfig,
+		DedupWindow:               dedupWindow,
+		ChangeFields:              changeFields,
+		ChangeStorePath:           cfg[KeyChangesStorePath],
+		InferSchema:               inferSchema,
+		FlattenFields:             flattenFields,
+		FieldAliases:              fieldAliases,
+		TicketFieldsRefreshPeriod: refreshPeriod,
+		Accounts:                  accounts,
 	}
 	return sourceConfig, nil
 }
+
+// parseAccounts returns the configs of the accounts, nil if the accounts aren't set. The configs of an account are
+// the zendesk configs, overridden by the configs of the account, with the subdomain as domain.
+func parseAccounts(cfg map[string]string) ([]config.Config, error) {
+	subdomains := splitList(cfg[KeyAccounts])
+	if len(subdomains) == 0 {
+		return nil, nil
+	}
+	for _, key := range []string{config.KeyDomain, config.KeyBaseURL} {
+		if cfg[key] != "" {
+			return nil, fmt.Errorf("%q and %q can't be set together", KeyAccounts, key)
+		}
+	}
+
+	seen := make(map[string]bool, len(subdomains))
+	accounts := make([]config.Config, 0, len(subdomains))
+	for _, subdomain := range subdomains {
+		if !subdomainPattern.MatchString(subdomain) {
+			return nil, fmt.Errorf("%q config value %q is not a valid subdomain", KeyAccounts, subdomain)
+		}
+		if seen[subdomain] {
+			return nil, fmt.Errorf("%q config value %q is listed more than once", KeyAccounts, subdomain)
+		}
+		seen[subdomain] = true
+
+		accountCfg := make(map[string]string)
+		for key, value := range cfg {
+			if strings.HasPrefix(key, zendeskKeyPrefix) {
+				accountCfg[key] = value
+			}
+		}
+		prefix := KeyAccounts + "." + subdomain + "."
+		for key, value := range cfg {
+			if name := strings.TrimPrefix(key, prefix); name != key {
+				accountCfg[zendeskKeyPrefix+name] = value
+			}
+		}
+		if accountCfg[config.KeyDomain] != "" {
+			return nil, fmt.Errorf("%q config value can't be set, the domain of the account is its subdomain", prefix+"domain")
+		}
+		accountCfg[config.KeyDomain] = subdomain
+
+		account, err := config.Parse(accountCfg)
+		if err != nil {
+			return nil, fmt.Errorf("account %q: %w", subdomain, err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// parseProjection returns the projection of the fields configs, nil if none is set
+func parseProjection(cfg map[string]string) (*projection.Projection, error) {
+	include := splitList(cfg[KeyFieldsInclude])
+	exclude := splitList(cfg[KeyFieldsExclude])
+	rules, err := projection.ParseRules(cfg[KeyFieldsRedact])
+	if err != nil {
+		return nil, fmt.Errorf("%q config value is invalid: %w", KeyFieldsRedact, err)
+	}
+	if len(include) == 0 && len(exclude) == 0 && len(rules) == 0 {
+		return nil, nil
+	}
+
+	salt, err := config.ResolveSecret(cfg[KeyFieldsHashSalt])
+	if err != nil {
+		return nil, fmt.Errorf("%q config value: %w", KeyFieldsHashSalt, err)
+	}
+	p, err := projection.New(include, exclude, rules, salt)
+	if err != nil {
+		return nil, fmt.Errorf("%q config value is invalid: %w", KeyFieldsRedact, err)
+	}
+	return p, nil
+}
+
+// parseBackfill returns the backfill of the backfill configs, nil if the start time isn't set.
+// The end time is zero if not set, the backfill then ends at the time the source is opened.
+func parseBackfill(cfg map[string]string) (*iterator.Backfill, error) {
+	if cfg[KeyBackfillStart] == "" {
+		return nil, nil
+	}
+	start, err := time.Parse(time.RFC3339, cfg[KeyBackfillStart])
+	if err != nil {
+		return nil, fmt.Errorf("%q config value should be a RFC3339 time: %w", KeyBackfillStart, err)
+	}
+
+	var end time.Time
+	if cfg[KeyBackfillEnd] != "" {
+		end, err = time.Parse(time.RFC3339, cfg[KeyBackfillEnd])
+		if err != nil {
+			return nil, fmt.Errorf("%q config value should be a RFC3339 time: %w", KeyBackfillEnd, err)
+		}
+	}
+	until := end
+	if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	if until.Sub(start) < time.Second {
+		return nil, fmt.Errorf("%q config value should be at least a second before %q", KeyBackfillStart, KeyBackfillEnd)
+	}
+
+	slices, err := parsePositiveInt(cfg, KeyBackfillSlices, defaultBackfillSlices)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := parsePositiveInt(cfg, KeyBackfillConcurrency, defaultBackfillConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator.Backfill{Start: start, End: end, Slices: slices, Concurrency: concurrency}, nil
+}
+
+// parseWindow returns the start and end times of the export window, zero if not set,
+// and the offset of the start time from the time the source is opened, nil if the start time isn't relative
+func parseWindow(cfg map[string]string) (time.Time, *time.Duration, time.Time, error) {
+	var start, end time.Time
+	var offset *time.Duration
+	var err error
+	switch {
+	case cfg[KeyStartTime] != "" && cfg[KeyStartFrom] != "":
+		return time.Time{}, nil, time.Time{}, fmt.Errorf("%q and %q can't be set together", KeyStartTime, KeyStartFrom)
+	case cfg[KeyStartTime] != "":
+		start, err = time.Parse(time.RFC3339, cfg[KeyStartTime])
+		if err != nil {
+			return time.Time{}, nil, time.Time{}, fmt.Errorf("%q config value should be a RFC3339 time: %w", KeyStartTime, err)
+		}
+	case cfg[KeyStartFrom] != "":
+		start, offset, err = parseStartFrom(cfg[KeyStartFrom])
+		if err != nil {
+			return time.Time{}, nil, time.Time{}, err
+		}
+	}
+	if cfg[KeyEndTime] != "" {
+		end, err = time.Parse(time.RFC3339, cfg[KeyEndTime])
+		if err != nil {
+			return time.Time{}, nil, time.Time{}, fmt.Errorf("%q config value should be a RFC3339 time: %w", KeyEndTime, err)
+		}
+	}
+	from := start
+	if offset != nil {
+		from = time.Now().UTC().Add(*offset)
+	}
+	if !from.IsZero() && !end.IsZero() && !from.Before(end) {
+		return time.Time{}, nil, time.Time{}, fmt.Errorf("%q config value should be before %q", startKey(cfg), KeyEndTime)
+	}
+	return start, offset, end, nil
+}
+
+// parseStartFrom returns the start time of the start from value,
+// or its offset from the time the source is opened if it is relative, zero for now
+func parseStartFrom(value string) (time.Time, *time.Duration, error) {
+	value = strings.TrimSpace(value)
+	var offset time.Duration
+	if strings.EqualFold(value, startFromNow) {
+		return time.Time{}, &offset, nil
+	}
+	if start, err := time.Parse(time.RFC3339, value); err == nil {
+		return start, nil, nil
+	}
+	offset, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("%q config value should be a RFC3339 time, a negative duration or %q, got %q", KeyStartFrom, startFromNow, value)
+	}
+	if offset > 0 {
+		return time.Time{}, nil, fmt.Errorf("%q config value should be a negative duration, i.e. -%s", KeyStartFrom, value)
+	}
+	return time.Time{}, &offset, nil
+}
+
+// startAt returns the start time of the entities without position, once the source is opened at the time,
+// zero to read them from the beginning
+func (c Config) startAt(opened time.Time) time.Time {
+	if c.StartOffset != nil {
+		return opened.Add(*c.StartOffset)
+	}
+	return c.StartTime
+}
+
+// backfillAt returns the backfill of the entities without position, once the source is opened at the time,
+// nil to use the snapshot
+func (c Config) backfillAt(opened time.Time) *iterator.Backfill {
+	if c.Backfill == nil || !c.Backfill.End.IsZero() {
+		return c.Backfill
+	}
+	backfill := *c.Backfill
+	backfill.End = opened
+	return &backfill
+}
+
+// startKey returns the key of the start time set in the config
+func startKey(cfg map[string]string) string {
+	if cfg[KeyStartFrom] != "" {
+		return KeyStartFrom
+	}
+	return KeyStartTime
+}
+
+// parseSearch returns the search query and object type of the search mode, the query is empty if not set
+func parseSearch(cfg map[string]string) (string, string, error) {
+	query := strings.TrimSpace(cfg[KeySearchQuery])
+	if query == "" {
+		return "", "", nil
+	}
+	if strings.TrimSpace(cfg[KeyEntities]) != "" {
+		return "", "", fmt.Errorf("%q and %q can't be set together", KeySearchQuery, KeyEntities)
+	}
+
+	objectType := strings.TrimSpace(cfg[KeySearchType])
+	switch objectType {
+	case "":
+		objectType = defaultSearchType
+	case "ticket", "user", "organization", "group":
+	default:
+		return "", "", fmt.Errorf("%q config value should be one of ticket, user, organization or group, got %q", KeySearchType, objectType)
+	}
+	return query, objectType, nil
+}
+
+// parseWebhook returns the webhook config, nil if the webhook address isn't set
+func parseWebhook(cfg map[string]string) (*webhook.Config, error) {
+	if cfg[KeyWebhookAddress] == "" {
+		return nil, nil
+	}
+	if cfg[KeyWebhookSecret] == "" {
+		return nil, fmt.Errorf("%q config value must be set to verify the webhook requests", KeyWebhookSecret)
+	}
+	if _, err := config.ResolveSecret(cfg[KeyWebhookSecret]); err != nil {
+		return nil, fmt.Errorf("%q config value: %w", KeyWebhookSecret, err)
+	}
+
+	path := cfg[KeyWebhookPath]
+	if path == "" {
+		path = defaultWebhookPath
+	}
+	tolerance, err := parseDuration(cfg, KeyWebhookTolerance, defaultWebhookTolerance)
+	if err != nil {
+		return nil, err
+	}
+	sweepPeriod, err := parseDuration(cfg, KeyWebhookSweepPeriod, defaultWebhookSweepPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook.Config{
+		Address:     cfg[KeyWebhookAddress],
+		Path:        path,
+		Secret:      cfg[KeyWebhookSecret],
+		ObjectField: strings.TrimSpace(cfg[KeyWebhookObjectField]),
+		Tolerance:   tolerance,
+		SweepPeriod: sweepPeriod,
+	}, nil
+}
+
+// parseTicketsOption parses the boolean config value of an option of the tickets, which can only be true when reading them
+func parseTicketsOption(cfg map[string]string, key, defaultValue string, entities []string, searchType string) (bool, error) {
+	value := cfg[key]
+	if value == "" {
+		value = defaultValue
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%q config value should be a boolean: %w", key, err)
+	}
+	if !enabled {
+		return false, nil
+	}
+
+	readsTickets := searchType == "ticket"
+	for _, entity := range entities {
+		if entity == zendesk.EntityTickets {
+			readsTickets = true
+		}
+	}
+	if !readsTickets {
+		return false, fmt.Errorf("%q config value can only be true when reading the tickets", key)
+	}
+	return true, nil
+}
+
+// parseAliases parses the comma separated list of `id:alias` keys of the custom fields, nil if empty
+func parseAliases(value string) (map[float64]string, error) {
+	items := splitList(value)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	aliases := make(map[float64]string, len(items))
+	used := make(map[string]bool, len(items))
+	for _, item := range items {
+		idValue, alias, _ := strings.Cut(item, ":")
+		id, err := strconv.ParseInt(strings.TrimSpace(idValue), 10, 64)
+		alias = strings.TrimSpace(alias)
+		if err != nil || alias == "" {
+			return nil, fmt.Errorf("%q config value should be a list of id:alias, got %q", KeyCustomFieldsAliases, item)
+		}
+		if _, ok := aliases[float64(id)]; ok || used[alias] {
+			return nil, fmt.Errorf("%q config value %q is listed more than once", KeyCustomFieldsAliases, item)
+		}
+		aliases[float64(id)] = alias
+		used[alias] = true
+	}
+	return aliases, nil
+}
+
+// parseDuration parses the positive duration config value, the default value is used if not set
+func parseDuration(cfg map[string]string, key, defaultValue string) (time.Duration, error) {
+	value := cfg[key]
+	if value == "" {
+		value = defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q config value should be a positive duration, got %q", key, value)
+	}
+	return d, nil
+}
+
+// parsePositiveInt parses the positive integer config value, the default value is returned if not set
+func parsePositiveInt(cfg map[string]string, key string, defaultValue int) (int, error) {
+	if cfg[key] == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(cfg[key])
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("%q config value should be a positive integer, got %q", key, cfg[key])
+	}
+	return value, nil
+}
+
+// splitList splits the comma separated list, ignoring empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseEntities splits the comma separated list of entities and validates each of them
+func parseEntities(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		value = defaultEntities
+	}
+
+	seen := make(map[string]bool)
+	entities := make([]string, 0)
+	for _, entity := range strings.Split(value, ",") {
+		entity = strings.TrimSpace(entity)
+		if entity == "" {
+			continue
+		}
+		if _, ok := zendesk.LookupEntity(entity); !ok {
+			return nil, fmt.Errorf("%q config value %q is not a supported entity, supported entities: %s", KeyEntities, entity, strings.Join(zendesk.EntityNames(), ","))
+		}
+		if seen[entity] {
+			return nil, fmt.Errorf("%q config value %q is listed more than once", KeyEntities, entity)
+		}
+		seen[entity] = true
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
@@ -20,6 +20,9 @@ import (
 	"time"
 
 	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/conduitio/conduit-connector-zendesk/source/iterator"
+	"github.com/conduitio/conduit-connector-zendesk/source/webhook"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -39,8 +42,11 @@ func TestParse(t *testing.T) {
 			},
 			want: Config{
 				PollingPeriod: time.Minute * 5,
+				Entities:      []string{zendesk.EntityTickets},
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -56,8 +62,11 @@ func TestParse(t *testing.T) {
 			},
 			want: Config{
 				PollingPeriod: time.Second * 6,
+				Entities:      []string{zendesk.EntityTickets},
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -72,8 +81,33 @@ func TestParse(t *testing.T) {
 			},
 			want: Config{
 				PollingPeriod: time.Second * 6,
+				Entities:      []string{zendesk.EntityTickets},
 				Config: config.Config{
 					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
+					UserName: "test@testlab.com",
+					APIToken: "gkdsaj)({jgo43646435#$!ga",
+				},
+			},
+		},
+		{
+			name: "Login with multiple entities",
+			config: map[string]string{
+				KeyEntities:        "tickets, users,organizations",
+				KeySnapshot:        "true",
+				config.KeyDomain:   "testlab",
+				config.KeyUserName: "test@testlab.com",
+				config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+			},
+			want: Config{
+				PollingPeriod: time.Second * 6,
+				Entities:      []string{zendesk.EntityTickets, zendesk.EntityUsers, zendesk.EntityOrganizations},
+				Snapshot:      true,
+				Config: config.Config{
+					Domain:   "testlab",
+					AuthType: config.AuthTypeBasic,
+					Timeout:  5 * time.Second,
 					UserName: "test@testlab.com",
 					APIToken: "gkdsaj)({jgo43646435#$!ga",
 				},
@@ -88,3 +122,419 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_InvalidEntities(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities string
+		snapshot string
+		err      string
+	}{
+		{
+			name:     "unsupported entity",
+			entities: "tickets,calls",
+			err:      `"entities" config value "calls" is not a supported entity, supported entities: articles,organizations,satisfaction_ratings,tickets,users`,
+		},
+		{
+			name:     "invalid snapshot",
+			entities: "tickets",
+			snapshot: "sometimes",
+			err:      `"snapshot" config value should be a boolean: strconv.ParseBool: parsing "sometimes": invalid syntax`,
+		},
+		{
+			name:     "duplicate entity",
+			entities: "tickets,users,tickets",
+			err:      `"entities" config value "tickets" is listed more than once`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(map[string]string{
+				KeyEntities:        tt.entities,
+				KeySnapshot:        tt.snapshot,
+				config.KeyDomain:   "testlab",
+				config.KeyUserName: "test@testlab.com",
+				config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+			})
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParse_Filter(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:   "testlab",
+		config.KeyUserName: "test@testlab.com",
+		config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+		KeyFilter:          `brand_id in (1, 2) and status != "closed"`,
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, `brand_id in (1, 2) and status != "closed"`, res.Filter.String())
+	assert.True(t, res.Filter.Match(map[string]interface{}{"brand_id": float64(2), "status": "open"}))
+
+	cfg[KeyFilter] = `brand_id in (1, 2`
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"filter" config value is not a valid filter: expected , or ) at position 17, got end of expression`)
+}
+
+func TestParse_Projection(t *testing.T) {
+	t.Setenv("ZENDESK_HASH_SALT", "dummy_salt")
+	cfg := map[string]string{
+		config.KeyDomain:   "testlab",
+		config.KeyUserName: "test@testlab.com",
+		config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+		KeyFieldsInclude:   "id, subject, requester",
+		KeyFieldsExclude:   "requester.phone",
+		KeyFieldsRedact:    "requester.email:hash",
+		KeyFieldsHashSalt:  "${env:ZENDESK_HASH_SALT}",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	payload, err := res.Projection.Apply(map[string]interface{}{
+		"id":          float64(1),
+		"description": "Call me at +1 555 0100",
+		"requester":   map[string]interface{}{"email": "jdoe@example.com", "phone": "+1 555 0100"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":        float64(1),
+		"requester": map[string]interface{}{"email": "9e70b9e96aba85669a100613bbf233b549f988bb774a0b5d2f41c47375b1e955"},
+	}, payload)
+
+	cfg[KeyFieldsHashSalt] = ""
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"fields.redact" config value is invalid: a salt is required to hash "requester.email"`)
+
+	// no projection when no field config is set
+	res, err = Parse(map[string]string{
+		config.KeyDomain:   "testlab",
+		config.KeyUserName: "test@testlab.com",
+		config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, res.Projection)
+}
+
+func TestParse_Backfill(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:       "testlab",
+		config.KeyUserName:     "test@testlab.com",
+		config.KeyAPIToken:     "gkdsaj)({jgo43646435#$!ga",
+		KeyBackfillStart:       "2015-01-01T00:00:00Z",
+		KeyBackfillEnd:         "2022-01-01T00:00:00Z",
+		KeyBackfillConcurrency: "2",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, &iterator.Backfill{
+		Start:       time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Slices:      8,
+		Concurrency: 2,
+	}, res.Backfill)
+
+	assert.Same(t, res.Backfill, res.backfillAt(time.Now()))
+
+	// the backfill ends when the source is opened by default
+	delete(cfg, KeyBackfillEnd)
+	res, err = Parse(cfg)
+	assert.NoError(t, err)
+	assert.True(t, res.Backfill.End.IsZero())
+	opened := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, opened, res.backfillAt(opened).End)
+	assert.True(t, res.Backfill.End.IsZero())
+
+	cfg[KeyBackfillEnd] = "2014-01-01T00:00:00Z"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"backfill.start" config value should be at least a second before "backfill.end"`)
+
+	cfg[KeyBackfillEnd] = "2022-01-01T00:00:00Z"
+	cfg[KeyBackfillSlices] = "0"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"backfill.slices" config value should be a positive integer, got "0"`)
+}
+
+func TestParse_Window(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:   "testlab",
+		config.KeyUserName: "test@testlab.com",
+		config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+		KeyStartTime:       "2022-05-01T00:00:00Z",
+		KeyEndTime:         "2022-06-01T00:00:00Z",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC), res.StartTime)
+	assert.Equal(t, time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), res.EndTime)
+
+	cfg[KeyEndTime] = "2022-05-01T00:00:00Z"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"startTime" config value should be before "endTime"`)
+
+	cfg[KeyEndTime] = "june"
+	_, err = Parse(cfg)
+	assert.ErrorContains(t, err, `"endTime" config value should be a RFC3339 time`)
+
+	delete(cfg, KeyEndTime)
+	cfg[KeyBackfillStart] = "2015-01-01T00:00:00Z"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"startTime" and "backfill.start" can't be set together`)
+}
+
+func TestParseStartFrom(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr string
+	}{{
+		value: "2022-05-01T00:00:00Z",
+		want:  time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+	}, {
+		value: "-720h",
+		want:  time.Date(2022, 5, 2, 12, 0, 0, 0, time.UTC),
+	}, {
+		value: "now",
+		want:  now,
+	}, {
+		value:   "720h",
+		wantErr: `"startFrom" config value should be a negative duration, i.e. -720h`,
+	}, {
+		value:   "yesterday",
+		wantErr: `"startFrom" config value should be a RFC3339 time, a negative duration or "now", got "yesterday"`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			start, offset, err := parseStartFrom(tt.value)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			// the relative values are resolved once the source is opened
+			assert.Equal(t, tt.want, Config{StartTime: start, StartOffset: offset}.startAt(now))
+		})
+	}
+}
+
+func TestParse_StartFrom(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:   "testlab",
+		config.KeyUserName: "test@testlab.com",
+		config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+		KeyStartFrom:       "-24h",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.True(t, res.StartTime.IsZero())
+	assert.Equal(t, -24*time.Hour, *res.StartOffset)
+
+	cfg[KeyStartTime] = "2022-05-01T00:00:00Z"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"startTime" and "startFrom" can't be set together`)
+
+	delete(cfg, KeyStartTime)
+	cfg[KeyEndTime] = "2022-05-01T00:00:00Z"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"startFrom" config value should be before "endTime"`)
+}
+
+func TestParse_Search(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:   "testlab",
+		config.KeyUserName: "test@testlab.com",
+		config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+		KeySearchQuery:     "type:ticket tags:vip",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "type:ticket tags:vip", res.SearchQuery)
+	assert.Equal(t, "ticket", res.SearchType)
+	assert.Equal(t, []string{zendesk.EntitySearch}, res.Entities)
+
+	cfg[KeySearchType] = "article"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"search.type" config value should be one of ticket, user, organization or group, got "article"`)
+
+	cfg[KeySearchType] = "user"
+	cfg[KeyEntities] = "users"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"search.query" and "entities" can't be set together`)
+}
+
+func TestParse_Webhook(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:      "testlab",
+		config.KeyUserName:    "test@testlab.com",
+		config.KeyAPIToken:    "gkdsaj)({jgo43646435#$!ga",
+		KeyWebhookAddress:     ":8080",
+		KeyWebhookSecret:      "signing-secret",
+		KeyWebhookObjectField: "ticket",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, &webhook.Config{
+		Address:     ":8080",
+		Path:        "/",
+		Secret:      "signing-secret",
+		ObjectField: "ticket",
+		Tolerance:   5 * time.Minute,
+		SweepPeriod: 15 * time.Minute,
+	}, res.Webhook)
+
+	cfg[KeyWebhookSweepPeriod] = "0s"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"webhook.sweepPeriod" config value should be a positive duration, got "0s"`)
+
+	delete(cfg, KeyWebhookSweepPeriod)
+	cfg[KeyEndTime] = "2022-06-01T00:00:00Z"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"webhook.address" and "endTime" can't be set together`)
+
+	delete(cfg, KeyEndTime)
+	delete(cfg, KeyWebhookSecret)
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"webhook.secret" config value must be set to verify the webhook requests`)
+}
+
+func TestParse_DedupWindow(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:   "testlab",
+		config.KeyUserName: "test@testlab.com",
+		config.KeyAPIToken: "gkdsaj)({jgo43646435#$!ga",
+		KeyDedupWindow:     "100",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, res.DedupWindow)
+
+	cfg[KeyDedupWindow] = "-1"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"dedup.window" config value should be a non-negative integer, got "-1"`)
+
+	cfg[KeyDedupWindow] = "100"
+	cfg[KeySearchQuery] = "tags:vip"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"dedup.window" and "search.query" can't be set together`)
+}
+
+func TestParse_Changes(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:    "testlab",
+		config.KeyUserName:  "test@testlab.com",
+		config.KeyAPIToken:  "gkdsaj)({jgo43646435#$!ga",
+		KeyChangesFields:    "status, priority,custom_fields",
+		KeyChangesStorePath: "/var/lib/conduit/zendesk-changes.log",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"status", "priority", "custom_fields"}, res.ChangeFields)
+	assert.Equal(t, "/var/lib/conduit/zendesk-changes.log", res.ChangeStorePath)
+
+	delete(cfg, KeyChangesStorePath)
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"changes.storePath" config value must be set to track "changes.fields"`)
+}
+
+func TestParse_Schema(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:             "testlab",
+		config.KeyUserName:           "test@testlab.com",
+		config.KeyAPIToken:           "gkdsaj)({jgo43646435#$!ga",
+		KeySchemaInfer:               "true",
+		KeyTicketFieldsRefreshPeriod: "1h",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.True(t, res.InferSchema)
+	assert.Equal(t, time.Hour, res.TicketFieldsRefreshPeriod)
+
+	cfg[KeyEntities] = "users"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"schema.infer" config value can only be true when reading the tickets`)
+
+	// searched tickets
+	delete(cfg, KeyEntities)
+	cfg[KeySearchQuery] = "tags:vip"
+	res, err = Parse(cfg)
+	assert.NoError(t, err)
+	assert.True(t, res.InferSchema)
+}
+
+func TestParse_CustomFields(t *testing.T) {
+	cfg := map[string]string{
+		config.KeyDomain:       "testlab",
+		config.KeyUserName:     "test@testlab.com",
+		config.KeyAPIToken:     "gkdsaj)({jgo43646435#$!ga",
+		KeyCustomFieldsFlatten: "true",
+		KeyCustomFieldsAliases: "360001:plan, 360002:refund_amount",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	assert.True(t, res.FlattenFields)
+	assert.Equal(t, map[float64]string{360001: "plan", 360002: "refund_amount"}, res.FieldAliases)
+	assert.Equal(t, 15*time.Minute, res.TicketFieldsRefreshPeriod)
+
+	cfg[KeyCustomFieldsAliases] = "plan"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"customFields.aliases" config value should be a list of id:alias, got "plan"`)
+
+	cfg[KeyCustomFieldsAliases] = "360001:plan,360002:plan"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"customFields.aliases" config value "360002:plan" is listed more than once`)
+
+	cfg[KeyCustomFieldsAliases] = "360001:plan"
+	cfg[KeyCustomFieldsFlatten] = "false"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"customFields.flatten" config value must be true to set "customFields.aliases"`)
+}
+
+func TestParse_Accounts(t *testing.T) {
+	t.Setenv("ACME_EU_API_TOKEN", "acme-eu-token")
+	cfg := map[string]string{
+		KeyAccounts:                   "acme, acme-eu",
+		config.KeyUserName:            "test@testlab.com",
+		config.KeyAPIToken:            "gkdsaj)({jgo43646435#$!ga",
+		"accounts.acme-eu.apiToken":   "${env:ACME_EU_API_TOKEN}",
+		"accounts.acme-eu.timeout":    "10s",
+		"accounts.acme-apac.apiToken": "not listed",
+	}
+	res, err := Parse(cfg)
+	assert.NoError(t, err)
+	if !assert.Len(t, res.Accounts, 2) {
+		return
+	}
+	assert.Equal(t, res.Accounts[0], res.Config)
+	assert.Equal(t, "acme", res.Accounts[0].Domain)
+	assert.Equal(t, "gkdsaj)({jgo43646435#$!ga", res.Accounts[0].APIToken)
+	assert.Equal(t, 5*time.Second, res.Accounts[0].Timeout)
+	// the configs of the account override the zendesk configs
+	assert.Equal(t, "acme-eu", res.Accounts[1].Domain)
+	assert.Equal(t, "test@testlab.com", res.Accounts[1].UserName)
+	assert.Equal(t, "${env:ACME_EU_API_TOKEN}", res.Accounts[1].APIToken)
+	assert.Equal(t, 10*time.Second, res.Accounts[1].Timeout)
+
+	cfg[KeyAccounts] = "acme,acme"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"accounts" config value "acme" is listed more than once`)
+
+	cfg[KeyAccounts] = "acme.zendesk.com"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"accounts" config value "acme.zendesk.com" is not a valid subdomain`)
+
+	cfg[KeyAccounts] = "acme"
+	cfg["accounts.acme.authType"] = "oauth"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `account "acme": either "zendesk.oauth.accessToken" or "zendesk.oauth.clientID" config value must be set`)
+
+	delete(cfg, "accounts.acme.authType")
+	cfg[config.KeyDomain] = "acme"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"accounts" and "zendesk.domain" can't be set together`)
+
+	delete(cfg, config.KeyDomain)
+	cfg[KeySchemaInfer] = "true"
+	_, err = Parse(cfg)
+	assert.EqualError(t, err, `"accounts" and "schema.infer" can't be set together`)
+}
@@ -0,0 +1,479 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed filter expression, matching the zendesk objects read by the source.
+//
+// An expression compares object fields with literal values, i.e. `brand_id in (1, 2) and status != "closed"`:
+//   - comparisons: `=`, `!=`, `<`, `<=`, `>`, `>=`, `in (...)` and `not in (...)`
+//   - logical operators: `and`, `or`, `not` and parentheses, `and` binds tighter than `or`
+//   - fields: object field names, nested fields are separated with dots, i.e. `via.channel`
+//   - values: double-quoted strings, numbers, `true`, `false` and `null`
+//
+// Missing fields are null. Comparisons between different types are false, except `!=` and `not in`, which are true.
+// Array fields, like tags, match if any element matches, `!=` and `not in` match if no element matches.
+type Filter struct {
+	expression string
+	root       node
+}
+
+// Parse parses the filter expression
+func Parse(expression string) (*Filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// Match reports whether the object matches the filter
+func (f *Filter) Match(object map[string]interface{}) bool {
+	return f.root.eval(object)
+}
+
+func (f *Filter) String() string {
+	return f.expression
+}
+
+type node interface {
+	eval(object map[string]interface{}) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(object map[string]interface{}) bool {
+	return n.left.eval(object) && n.right.eval(object)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(object map[string]interface{}) bool {
+	return n.left.eval(object) || n.right.eval(object)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(object map[string]interface{}) bool {
+	return !n.operand.eval(object)
+}
+
+// comparisonNode compares the field value with the values, a single one unless the operator is in or not in
+type comparisonNode struct {
+	field  []string
+	op     string
+	values []interface{}
+}
+
+func (n comparisonNode) eval(object map[string]interface{}) bool {
+	value := lookup(object, n.field)
+	switch n.op {
+	case "!=":
+		return !matchAny(value, func(v interface{}) bool { return equal(v, n.values[0]) })
+	case "not in":
+		return !matchAny(value, func(v interface{}) bool { return in(v, n.values) })
+	case "in":
+		return matchAny(value, func(v interface{}) bool { return in(v, n.values) })
+	case "=":
+		return matchAny(value, func(v interface{}) bool { return equal(v, n.values[0]) })
+	default:
+		return matchAny(value, func(v interface{}) bool { return compare(v, n.op, n.values[0]) })
+	}
+}
+
+// lookup returns the value of the nested field, nil if it is missing
+func lookup(object map[string]interface{}, field []string) interface{} {
+	var value interface{} = object
+	for _, name := range field {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[name]
+	}
+	return value
+}
+
+// matchAny applies the match to the value, or to each element of an array value
+func matchAny(value interface{}, match func(interface{}) bool) bool {
+	list, ok := value.([]interface{})
+	if !ok {
+		return match(value)
+	}
+	for _, v := range list {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func in(value interface{}, values []interface{}) bool {
+	for _, v := range values {
+		if equal(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case float64:
+		b, ok := b.(float64)
+		return ok && a == b
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	default:
+		return false
+	}
+}
+
+// compare orders numbers numerically and strings lexically, which orders RFC3339 timestamps in time
+func compare(a interface{}, op string, b interface{}) bool {
+	var cmp int
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(a, b)
+	default:
+		return false
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+// expect consumes the next token, which must be of the kind and text, case insensitive
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.take()
+	if tok.kind != kind || !strings.EqualFold(tok.text, text) {
+		return fmt.Errorf("expected %s at position %d, got %s", text, tok.pos, tok)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.take()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.take()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch tok := p.peek(); {
+	case tok.is("not"):
+		p.take()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	case tok.kind == tokenPunct && tok.text == "(":
+		p.take()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	tok := p.take()
+	if tok.kind != tokenIdent || tok.isKeyword() {
+		return nil, fmt.Errorf("expected a field at position %d, got %s", tok.pos, tok)
+	}
+	field := strings.Split(tok.text, ".")
+
+	op := p.take()
+	switch {
+	case op.kind == tokenOperator:
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		operator := op.text
+		if operator == "==" {
+			operator = "="
+		}
+		return comparisonNode{field: field, op: operator, values: []interface{}{value}}, nil
+	case op.is("in"):
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonNode{field: field, op: "in", values: values}, nil
+	case op.is("not"):
+		if err := p.expect(tokenIdent, "in"); err != nil {
+			return nil, err
+		}
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonNode{field: field, op: "not in", values: values}, nil
+	default:
+		return nil, fmt.Errorf("expected an operator after %q at position %d, got %s", tok.text, op.pos, op)
+	}
+}
+
+func (p *parser) parseList() ([]interface{}, error) {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if tok := p.take(); tok.kind == tokenPunct && tok.text == ")" {
+			return values, nil
+		} else if tok.kind != tokenPunct || tok.text != "," {
+			return nil, fmt.Errorf("expected , or ) at position %d, got %s", tok.pos, tok)
+		}
+	}
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	tok := p.take()
+	switch {
+	case tok.kind == tokenString:
+		return tok.value, nil
+	case tok.kind == tokenNumber:
+		return tok.value, nil
+	case tok.is("true"):
+		return true, nil
+	case tok.is("false"):
+		return false, nil
+	case tok.is("null"):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("expected a value at position %d, got %s", tok.pos, tok)
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenPunct
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenIdent:
+		return "identifier"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenOperator:
+		return "operator"
+	case tokenPunct:
+		return "punctuation"
+	default:
+		return "end of expression"
+	}
+}
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{} // parsed value of strings and numbers
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return t.kind.String()
+	}
+	return strconv.Quote(t.text)
+}
+
+// is reports whether the token is the keyword, keywords are case insensitive
+func (t token) is(keyword string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (t token) isKeyword() bool {
+	for _, keyword := range []string{"and", "or", "not", "in", "true", "false", "null"} {
+		if t.is(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(' || r == ')' || r == ',':
+			i++
+			tokens = append(tokens, token{kind: tokenPunct, text: string(r), pos: start})
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			text := string(runes[start:i])
+			if text == "!" {
+				return nil, fmt.Errorf("unexpected \"!\" at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: text, pos: start})
+		case r == '"':
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			text := string(runes[start:i])
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", start, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, value: value, pos: start})
+		case r == '-' || unicode.IsDigit(r):
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: start})
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(runes) && (runes[i] == '_' || runes[i] == '.' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", r, start)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
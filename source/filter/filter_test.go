@@ -0,0 +1,101 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	var ticket map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"id": 35436,
+		"brand_id": 360000123,
+		"group_id": 20,
+		"status": "open",
+		"priority": null,
+		"tags": ["vip", "enterprise"],
+		"updated_at": "2022-05-10T12:00:00Z",
+		"via": {"channel": "email"}
+	}`), &ticket)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `status = "open"`, want: true},
+		{expression: `status == "open"`, want: true},
+		{expression: `status != "closed"`, want: true},
+		{expression: `brand_id in (360000123, 360000456) and status != "closed"`, want: true},
+		{expression: `brand_id in (360000456)`, want: false},
+		{expression: `group_id not in (10, 30)`, want: true},
+		{expression: `group_id >= 20 and group_id < 21`, want: true},
+		{expression: `group_id > 20`, want: false},
+		{expression: `updated_at > "2022-05-01T00:00:00Z"`, want: true},
+		{expression: `via.channel = "email"`, want: true},
+		{expression: `via.missing.field = null`, want: true},
+		{expression: `priority = null and assignee_id = null`, want: true},
+		{expression: `tags = "vip"`, want: true},
+		{expression: `tags in ("trial", "enterprise")`, want: true},
+		{expression: `tags != "vip"`, want: false},
+		{expression: `status = 1`, want: false},
+		{expression: `status > 1`, want: false},
+		{expression: `status = "closed" or group_id = 20 and not (tags = "spam")`, want: true},
+		{expression: `(status = "closed" or group_id = 20) and tags = "spam"`, want: false},
+		{expression: `NOT status = "open"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			f, err := Parse(tt.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, f.Match(ticket))
+			assert.Equal(t, tt.expression, f.String())
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string
+	}{
+		{expression: ``, err: "expected a field at position 0, got end of expression"},
+		{expression: `status`, err: "expected an operator after \"status\" at position 6, got end of expression"},
+		{expression: `status = "open`, err: "unterminated string at position 9"},
+		{expression: `status = open`, err: "expected a value at position 9, got \"open\""},
+		{expression: `brand_id in 1, 2`, err: "expected ( at position 12, got \"1\""},
+		{expression: `brand_id in (1 2)`, err: "expected , or ) at position 15, got \"2\""},
+		{expression: `brand_id not (1)`, err: "expected in at position 13, got \"(\""},
+		{expression: `(status = "open"`, err: "expected ) at position 16, got end of expression"},
+		{expression: `status = "open" status = "new"`, err: "unexpected \"status\" at position 16"},
+		{expression: `status ! "open"`, err: "unexpected \"!\" at position 7"},
+		{expression: `and = 1`, err: "expected a field at position 0, got \"and\""},
+		{expression: `status = "open" & group_id = 1`, err: "unexpected '&' at position 16"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := Parse(tt.expression)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
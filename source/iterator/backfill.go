@@ -0,0 +1,166 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"context"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+)
+
+// Backfill splits the export of the objects updated from Start till End in time slices,
+// read by Concurrency cursors in parallel. The cursors share the client, and so its rate limit.
+type Backfill struct {
+	Start       time.Time
+	End         time.Time
+	Slices      int
+	Concurrency int
+}
+
+// sliceTask is a backfill slice left to read
+type sliceTask struct {
+	entity string
+	index  int // index of the slice in the backfill position of the entity
+	cursor exportCursor
+}
+
+// position returns the position of an entity starting the backfill, the entity is polled from the backfill end once it completes
+func (b *Backfill) position() position.EntityPosition {
+	start, end := b.Start.Truncate(time.Second), b.End.Truncate(time.Second)
+	slices := b.Slices
+	if total := int(end.Sub(start) / time.Second); slices > total {
+		slices = total
+	}
+
+	pos := position.EntityPosition{LastModified: end.Add(-time.Second)}
+	if slices <= 0 {
+		return pos
+	}
+	size := (end.Sub(start) / time.Duration(slices)).Truncate(time.Second)
+	for i := 0; i < slices; i++ {
+		slice := position.SlicePosition{Start: start.Add(time.Duration(i) * size), End: start.Add(time.Duration(i+1) * size)}
+		if i == slices-1 {
+			slice.End = end
+		}
+		pos.Backfill = append(pos.Backfill, slice)
+	}
+	return pos
+}
+
+func (b *Backfill) concurrency() int {
+	if b == nil || b.Concurrency < 1 {
+		return 1
+	}
+	return b.Concurrency
+}
+
+// newSliceCursor returns the cursor resuming the slice, the objects of the dedup window are skipped
+func newSliceCursor(client *zendesk.Client, entity zendesk.Entity, slice position.SlicePosition, emitted []position.EmittedObject) exportCursor {
+	// the cursor starts the export one second after its start time
+	startTime := slice.Start.Add(-time.Second)
+	var ids []float64
+	if !slice.LastModified.IsZero() {
+		startTime = slice.LastModified
+		ids = emittedAt(emitted, slice.LastModified)
+	}
+	cursor := newExportCursor(client, entity, startTime, ids)
+	cursor.SetEndTime(slice.End)
+	return cursor
+}
+
+// startBackfill reads the slices with concurrent workers, each reading one slice after another
+func (c *CDCIterator) startBackfill(ctx context.Context, slices []sliceTask, concurrency int) {
+	tasks := make(chan sliceTask, len(slices))
+	for _, task := range slices {
+		tasks <- task
+	}
+	close(tasks)
+
+	if concurrency > len(slices) {
+		concurrency = len(slices)
+	}
+	for i := 0; i < concurrency; i++ {
+		c.tomb.Go(func() error {
+			for task := range tasks {
+				if err := c.backfill(ctx, task); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// backfill reads the slice till its cursor is done, as fast as the rate limit allows
+func (c *CDCIterator) backfill(ctx context.Context, task sliceTask) error {
+	for !task.cursor.Done() {
+		records, err := task.cursor.FetchRecords(ctx)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 && !task.cursor.Done() {
+			// rate limited or failed request, retry in the next poll
+			select {
+			case <-c.tomb.Dying():
+				return c.tomb.Err()
+			case <-time.After(c.pollingPeriod):
+			}
+			continue
+		}
+
+		err = c.push(task.entity, records, func(pos, recordPos position.EntityPosition) position.EntityPosition {
+			pos.Backfill = append([]position.SlicePosition(nil), pos.Backfill...)
+			pos.Backfill[task.index].LastModified = recordPos.LastModified
+			pos.Backfill[task.index].ID = recordPos.ID
+			return pos
+		})
+		if err != nil {
+			return err
+		}
+	}
+	c.completeSlice(ctx, task)
+	return nil
+}
+
+// completeSlice marks the slice done, the entity is polled once all its slices are done
+func (c *CDCIterator) completeSlice(ctx context.Context, task sliceTask) {
+	c.posMux.Lock()
+	defer c.posMux.Unlock()
+
+	pos := c.positions[task.entity]
+	pos.Backfill = append([]position.SlicePosition(nil), pos.Backfill...)
+	pos.Backfill[task.index].Done = true
+
+	c.backfilling[task.entity]--
+	if c.backfilling[task.entity] == 0 {
+		pos.Backfill = nil
+		sdk.Logger(ctx).Info().
+			Str("entity", task.entity).
+			Time("last_modified_time", pos.LastModified).
+			Msg("backfill completed, switching to CDC mode")
+	}
+	c.positions[task.entity] = pos
+}
+
+func (c *CDCIterator) isBackfilling(entity string) bool {
+	c.posMux.Lock()
+	defer c.posMux.Unlock()
+	return c.backfilling[entity] > 0
+}
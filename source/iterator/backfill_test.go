@@ -0,0 +1,105 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk/zendesktest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCDCIterator_Backfill(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	server := zendesktest.NewServer()
+	defer server.Close()
+	server.SetPageSize(2)
+	// an older ticket, updated before the backfill start
+	addTickets(t, server, 0, 1)
+	created, err := server.AddTickets(ticketList(1, 20)...)
+	assert.NoError(t, err)
+	start, err := time.Parse(time.RFC3339, created[0]["updated_at"].(string))
+	assert.NoError(t, err)
+
+	client := zendesk.NewClient(server.URL, zendesk.Retry(3, time.Millisecond))
+	opts := Options{Backfill: &Backfill{Start: start, End: start.Add(20 * time.Second), Slices: 4, Concurrency: 2}}
+
+	// backfill, interrupted after 12 records
+	cdc, err := NewCDCIterator(ctx, client, 10*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, true, opts, position.SourcePosition{})
+	assert.NoError(t, err)
+	got := readRecords(ctx, t, cdc, 12)
+	cdc.Stop()
+
+	// every slice resumes from the position of the last read record
+	sp, err := position.ParseSourcePosition(got[len(got)-1].Position)
+	assert.NoError(t, err)
+	assert.Len(t, sp.Entities[zendesk.EntityTickets].Backfill, 4)
+	cdc, err = NewCDCIterator(ctx, client, 10*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, true, opts, sp)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+	got = append(got, readRecords(ctx, t, cdc, 8)...)
+
+	// CDC from the backfill end, once the backfill completes
+	addTickets(t, server, 21, 3)
+	cdcRecords := readRecords(ctx, t, cdc, 3)
+
+	readCtx, readCancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer readCancel()
+	rec, err := cdc.Next(readCtx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected record %s", rec.Key)
+
+	// the slices are read concurrently, each ticket of the backfill range is read once
+	keys := make([]int, 0, len(got))
+	for _, r := range got {
+		key, err := strconv.Atoi(string(r.Key.Bytes()))
+		assert.NoError(t, err)
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21}, keys)
+
+	for i, r := range cdcRecords {
+		assert.Equal(t, strconv.Itoa(22+i), string(r.Key.Bytes()))
+		pos, err := position.ParseSourcePosition(r.Position)
+		assert.NoError(t, err)
+		assert.Nil(t, pos.Entities[zendesk.EntityTickets].Backfill)
+	}
+}
+
+func TestBackfill_position(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	pos := (&Backfill{Start: start, End: start.Add(10 * time.Second), Slices: 3}).position()
+	assert.Equal(t, start.Add(9*time.Second), pos.LastModified)
+	assert.Equal(t, []position.SlicePosition{
+		{Start: start, End: start.Add(3 * time.Second)},
+		{Start: start.Add(3 * time.Second), End: start.Add(6 * time.Second)},
+		{Start: start.Add(6 * time.Second), End: start.Add(10 * time.Second)},
+	}, pos.Backfill)
+
+	// slices are at least a second long
+	pos = (&Backfill{Start: start, End: start.Add(2 * time.Second), Slices: 8}).position()
+	assert.Len(t, pos.Backfill, 2)
+}
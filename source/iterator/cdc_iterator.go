@@ -23,7 +23,11 @@ import (
 	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/source/changes"
+	"github.com/conduitio/conduit-connector-zendesk/source/filter"
 	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/source/projection"
+	"github.com/conduitio/conduit-connector-zendesk/source/webhook"
 	"github.com/conduitio/conduit-connector-zendesk/zendesk"
 	"gopkg.in/tomb.v2"
 )
@@ -32,53 +36,252 @@ type ZendeskCursor interface {
 	FetchRecords(ctx context.Context) ([]sdk.Record, error)
 }
 
+// boundedCursor is a cursor reading the objects updated till an end time
+type boundedCursor interface {
+	Done() bool
+}
+
+// pagedCursor is a cursor reporting whether more pages of the export can be fetched right away
+type pagedCursor interface {
+	More() bool
+}
+
+// movingCursor is a cursor whose position can move without records, i.e. once a search run completes on an empty page
+type movingCursor interface {
+	Position() (position.EntityPosition, bool)
+}
+
 //go:generate mockery --name=ZendeskCursor
 
+const (
+	// MetadataEntity is the record metadata key holding the name of the entity the record belongs to
+	MetadataEntity = "entity"
+	// MetadataWebhook is set to "true" for the records of the objects received by the webhook
+	MetadataWebhook = "webhook"
+	// MetadataSubdomain is the record metadata key holding the subdomain of the account the record belongs to,
+	// set when reading multiple accounts
+	MetadataSubdomain = "subdomain"
+)
+
+// Account is a zendesk account read along with other accounts, the records are tagged with its subdomain
+type Account struct {
+	Subdomain string
+	Client    *zendesk.Client
+}
+
+// stream is an entity read from an account
+type stream struct {
+	name    string // name of the entity, prefixed by the subdomain of the account when it has one
+	entity  zendesk.Entity
+	account Account
+}
+
 type CDCIterator struct {
-	lastModifiedTime time.Time         // ticket last updated time
-	tomb             *tomb.Tomb        // new tomb
-	ticker           *time.Ticker      // records time interval for next iteration
-	caches           chan []sdk.Record // cache to store array of tickets
-	buffer           chan sdk.Record   // buffer to store individual ticket object
-	cursor           ZendeskCursor
-	mux              *sync.Mutex // mux to avoid race condition while setting custom cursor
+	positions     map[string]position.EntityPosition // last position of each entity being read
+	tomb          *tomb.Tomb                         // new tomb
+	ticker        *time.Ticker                       // records time interval for next iteration
+	pollingPeriod time.Duration                      // time interval for next iteration
+	caches        chan []sdk.Record                  // cache to store array of records
+	buffer        chan sdk.Record                    // buffer to store individual record
+	entities      []string                           // names of the entities being read, in the order cursors are polled, `<subdomain>/<entity>` with multiple accounts
+	streams       map[string]stream                  // entity and account read under each entity name
+	cursors       map[string]ZendeskCursor           // cursor of each entity being read
+	mux           *sync.Mutex                        // mux to avoid race condition while setting custom cursor
+	posMux        *sync.Mutex                        // mux to keep the positions in the order records are pushed, when pushed concurrently
+	backfilling   map[string]int                     // number of backfill slices left for each entity, which is polled once none is left
+	completed     map[string]bool                    // entities read till the end time, which aren't polled anymore
+	finished      chan struct{}                      // closed once the records of every entity are flushed, with an end time
+	converters    map[string]*zendesk.Cursor         // cursors converting the objects received by the webhook, of each entity
+	delivered     map[string]map[float64]time.Time   // update time of the objects received by the webhook, till the export reaches them
+	deliveredMux  *sync.Mutex                        // mux guarding the delivered objects
+	windows       map[string]*window                 // dedup window of each entity, none without deduplication
+	transform     transform                          // filter and shape of the records read by the cursors
+	markCompleted map[string]bool                    // entities whose snapshot completed on a dropped record, marked on the next record instead
+	sweep         bool                               // the exports are read till their end on every tick, and right away on start, along with the webhook
+}
+
+// Options are the optional settings of the iterator
+type Options struct {
+	Filter     *filter.Filter         // records of the objects not matching the filter are dropped, if not nil
+	Projection *projection.Projection // projection of the payloads, if not nil
+	Backfill   *Backfill              // backfill of the entities without position, instead of the snapshot, if not nil
+	StartTime  time.Time              // objects of the entities without position are read from the start time, if not zero
+	EndTime    time.Time              // objects updated from the end time on aren't read, the iterator is done once every entity reaches it
+	// Events are the objects received by the webhook, if not nil. The exports then only sweep the objects missed by the webhook,
+	// reading every page on each tick, and right away on start.
+	Events <-chan webhook.Event
+	// Accounts are the accounts read instead of the account of the client, each with its own cursors, if not empty.
+	// The position of the entities of each account is kept under `<subdomain>/<entity>`, and the keys are prefixed by `<subdomain>:`.
+	Accounts []Account
+	// CustomFields flattens the custom fields of the ticket payloads, if not nil
+	CustomFields *zendesk.CustomFields
+	// Changes drops the records whose tracked fields didn't change since the last record emitted for the same object, if not nil.
+	// The tracked fields are hashed once the custom fields are flattened, before the projection.
+	Changes *changes.Detector
+	// DedupWindow is the number of the last emitted objects remembered in the position of each entity,
+	// records of the objects already emitted with the same update time are dropped. Zero disables the deduplication.
+	DedupWindow int
 }
 
-// NewCDCIterator will initialize CDCIterator parameters and also initialize goroutine to fetch records from server
+// NewCDCIterator will initialize CDCIterator parameters and also initialize goroutine to fetch records from server.
+// Custom cursors, if passed, replace the cursors of the entities at the same index, the entities of each account following the previous account.
 func NewCDCIterator(
 	ctx context.Context,
-	username, apiToken, domain string, // config params
+	client *zendesk.Client,
 	pollingPeriod time.Duration,
-	tp position.TicketPosition,
+	entities []zendesk.Entity,
+	snapshot bool,
+	opts Options,
+	sp position.SourcePosition,
 	cursors ...ZendeskCursor,
 ) (*CDCIterator, error) {
 	tmbWithCtx, _ := tomb.WithContext(ctx)
-	lastModified := tp.LastModified
-	if lastModified.IsZero() {
-		lastModified = time.Unix(0, 0)
+
+	cdc := &CDCIterator{
+		tomb:          tmbWithCtx,
+		caches:        make(chan []sdk.Record, 1),
+		buffer:        make(chan sdk.Record, 1),
+		ticker:        time.NewTicker(pollingPeriod),
+		pollingPeriod: pollingPeriod,
+		positions:     make(map[string]position.EntityPosition, len(entities)),
+		entities:      make([]string, 0, len(entities)),
+		cursors:       make(map[string]ZendeskCursor, len(entities)),
+		streams:       make(map[string]stream, len(entities)),
+		mux:           &sync.Mutex{},
+		posMux:        &sync.Mutex{},
+		backfilling:   make(map[string]int),
+		completed:     make(map[string]bool),
+		finished:      make(chan struct{}),
+		converters:    make(map[string]*zendesk.Cursor),
+		delivered:     make(map[string]map[float64]time.Time),
+		deliveredMux:  &sync.Mutex{},
+		windows:       make(map[string]*window),
+		transform:     newTransform(opts),
+		markCompleted: make(map[string]bool),
+		sweep:         opts.Events != nil,
 	}
 
-	var cursor ZendeskCursor = zendesk.NewCursor(username, apiToken, domain, lastModified)
-	if len(cursors) > 0 {
-		cursor = cursors[0]
+	accounts := opts.Accounts
+	if len(accounts) == 0 {
+		accounts = []Account{{Client: client}}
 	}
+	var slices []sliceTask
+	for i, s := range streamsOf(accounts, entities) {
+		entity, name := s.entity, s.name
+		pos, found := sp.Entities[name]
+		if !found && opts.Backfill != nil {
+			pos = opts.Backfill.position()
+			sdk.Logger(ctx).Info().Str("entity", name).Int("slices", len(pos.Backfill)).Msg("starting backfill")
+		} else if !found && !opts.StartTime.IsZero() {
+			// the cursor starts the export one second after the last modified time
+			pos.LastModified = opts.StartTime.Add(-time.Second)
+		}
+		if pos.LastModified.IsZero() {
+			pos.LastModified = time.Unix(0, 0)
+		}
+		if opts.DedupWindow > 0 {
+			cdc.windows[name] = newWindow(opts.DedupWindow, pos.Emitted)
+			pos.Emitted = cdc.windows[name].resumable(pos)
+		}
 
-	cdc := &CDCIterator{
-		tomb:             tmbWithCtx,
-		caches:           make(chan []sdk.Record, 1),
-		buffer:           make(chan sdk.Record, 1),
-		ticker:           time.NewTicker(pollingPeriod),
-		lastModifiedTime: lastModified,
-		cursor:           cursor,
-		mux:              &sync.Mutex{},
+		for index, slice := range pos.Backfill {
+			if slice.Done {
+				continue
+			}
+			slices = append(slices, sliceTask{
+				entity: name,
+				index:  index,
+				cursor: newSliceCursor(s.account.Client, entity, slice, pos.Emitted),
+			})
+			cdc.backfilling[name]++
+		}
+		if cdc.backfilling[name] == 0 {
+			pos.Backfill = nil
+		}
+
+		zendeskCursor := newExportCursor(s.account.Client, entity, pos.LastModified, emittedAt(pos.Emitted, pos.LastModified))
+		zendeskCursor.SetEndTime(opts.EndTime)
+		if search, ok := zendeskCursor.(*zendesk.SearchCursor); ok {
+			// resume the search run interrupted by the restart, if any, after the last record read
+			search.Resume(pos)
+		}
+		switch {
+		case pos.Backfill != nil:
+			// the entity is polled once the backfill completes
+		case pos.SnapshotEnd != nil:
+			// resume the snapshot interrupted by the restart, with the same end time
+			zendeskCursor.StartSnapshot(*pos.SnapshotEnd)
+		case snapshot && !found:
+			// the end is persisted with the records of the other entities, till the entity emits its own
+			end := time.Now().UTC()
+			pos.SnapshotEnd = &end
+			zendeskCursor.StartSnapshot(end)
+			sdk.Logger(ctx).Info().Str("entity", name).Msg("starting snapshot")
+		}
+
+		if opts.Events != nil {
+			cdc.converters[name] = zendesk.NewCursor(s.account.Client, entity, time.Time{})
+		}
+
+		var cursor ZendeskCursor = zendeskCursor
+		if i < len(cursors) {
+			cursor = cursors[i]
+		}
+
+		cdc.positions[name] = pos
+		cdc.entities = append(cdc.entities, name)
+		cdc.cursors[name] = cursor
+		cdc.streams[name] = s
 	}
 
 	cdc.tomb.Go(cdc.startCDC(ctx))
 	cdc.tomb.Go(cdc.flush)
+	if len(slices) > 0 {
+		cdc.startBackfill(ctx, slices, opts.Backfill.concurrency())
+	}
+	if opts.Events != nil {
+		cdc.tomb.Go(cdc.receive(ctx, opts.Events))
+	}
 
 	return cdc, nil
 }
 
+// exportCursor is the cursor of the export of an entity, either time based or search
+type exportCursor interface {
+	ZendeskCursor
+	boundedCursor
+	StartSnapshot(end time.Time)
+	SetEndTime(end time.Time)
+}
+
+// newExportCursor returns the cursor of the entity reading the objects updated after the start time,
+// or from the start time on, skipping the objects of the ids already emitted at that time, if any
+func newExportCursor(client *zendesk.Client, entity zendesk.Entity, startTime time.Time, emitted []float64) exportCursor {
+	if entity.Pagination == zendesk.PaginationSearch {
+		return zendesk.NewSearchCursor(client, entity, startTime)
+	}
+	cursor := zendesk.NewCursor(client, entity, startTime)
+	if len(emitted) > 0 {
+		cursor.ResumeInclusive(emitted)
+	}
+	return cursor
+}
+
+// streamsOf returns the entities of every account, in the order they are polled
+func streamsOf(accounts []Account, entities []zendesk.Entity) []stream {
+	streams := make([]stream, 0, len(accounts)*len(entities))
+	for _, account := range accounts {
+		for _, entity := range entities {
+			name := entity.Name
+			if account.Subdomain != "" {
+				name = account.Subdomain + "/" + entity.Name
+			}
+			streams = append(streams, stream{name: name, entity: entity, account: account})
+		}
+	}
+	return streams
+}
+
 // HasNext return true when buffer is not empty
 func (c *CDCIterator) HasNext(_ context.Context) bool {
 	return len(c.buffer) > 0 || !c.tomb.Alive() // return true in case of go routines dying, error will be returned by Next
@@ -96,37 +299,218 @@ func (c *CDCIterator) Next(ctx context.Context) (sdk.Record, error) {
 	}
 }
 
-// startCDC fetches records and set next position with lastmodified time of the ticket
+// Done reports whether every record updated before the end time was read, always false without end time
+func (c *CDCIterator) Done() bool {
+	select {
+	case <-c.finished:
+		return len(c.buffer) == 0
+	default:
+		return false
+	}
+}
+
+// startCDC fetches records from the cursors of all the entities, one after another,
+// and sets the composite position of the records with the last position of each entity.
+// The records are all pushed once every entity is read till the end time.
 func (c *CDCIterator) startCDC(ctx context.Context) func() error {
 	return func() error {
-		defer close(c.caches)
-		for {
+		if c.sweep {
+			// the objects missed while the webhook wasn't running are swept without waiting for the first tick
+			if err := c.poll(ctx); err != nil {
+				return err
+			}
+		}
+		for len(c.completed) < len(c.entities) {
 			select {
 			case <-c.tomb.Dying():
 				return c.tomb.Err()
 			case <-c.ticker.C:
-				c.mux.Lock()
-				records, err := c.cursor.FetchRecords(ctx)
-				c.mux.Unlock() // avoid defer, to stop locking the cursor for long duration, while it is not being used
-				if err != nil {
+				if err := c.poll(ctx); err != nil {
 					return err
 				}
-				if len(records) == 0 {
-					continue
-				}
-				select {
-				case c.caches <- records:
-					pos, err := position.ParsePosition(records[len(records)-1].Position)
-					if err != nil {
-						return err
-					}
-					c.lastModifiedTime = pos.LastModified
-				case <-c.tomb.Dying():
-					return c.tomb.Err()
+			}
+		}
+
+		// the backfill workers are done too, as the entities are polled once backfilled
+		close(c.caches)
+		<-c.tomb.Dying()
+		return c.tomb.Err()
+	}
+}
+
+// poll fetches the next page of every entity, or every page till the end of the export when sweeping
+func (c *CDCIterator) poll(ctx context.Context) error {
+	for _, entity := range c.entities {
+		if c.isBackfilling(entity) || c.completed[entity] {
+			continue
+		}
+		for {
+			if err := c.fetch(ctx, entity); err != nil {
+				return err
+			}
+			if !c.sweep || !c.hasMore(entity) || !c.tomb.Alive() {
+				break
+			}
+		}
+		if c.isDone(entity) {
+			c.completed[entity] = true
+			sdk.Logger(ctx).Info().Str("entity", entity).Msg("entity read till the end time")
+		}
+	}
+	return nil
+}
+
+// hasMore reports whether the cursor of the entity has more pages of the export to fetch right away
+func (c *CDCIterator) hasMore(entity string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	cursor, ok := c.cursors[entity].(pagedCursor)
+	return ok && cursor.More()
+}
+
+// isDone reports whether the cursor of the entity read every object updated before the end time
+func (c *CDCIterator) isDone(entity string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	cursor, ok := c.cursors[entity].(boundedCursor)
+	return ok && cursor.Done()
+}
+
+// fetch reads the next batch of records for the entity and pushes them to the cache
+func (c *CDCIterator) fetch(ctx context.Context, entity string) error {
+	c.mux.Lock()
+	records, err := c.cursors[entity].FetchRecords(ctx)
+	var moved *position.EntityPosition
+	if cursor, ok := c.cursors[entity].(movingCursor); ok {
+		if pos, ok := cursor.Position(); ok {
+			moved = &pos
+		}
+	}
+	c.mux.Unlock() // avoid defer, to stop locking the cursor for long duration, while it is not being used
+	if err != nil {
+		return err
+	}
+	if moved != nil {
+		c.move(entity, *moved)
+	}
+	records, err = c.skipDelivered(entity, records)
+	if err != nil {
+		return err
+	}
+	return c.push(entity, records, func(_, recordPos position.EntityPosition) position.EntityPosition {
+		return recordPos
+	})
+}
+
+// push sets the composite position of the records and pushes them to the cache, the update returns the position
+// of the entity once the record is read, from the current position of the entity and the position of the record
+func (c *CDCIterator) push(entity string, records []sdk.Record, update func(pos, recordPos position.EntityPosition) position.EntityPosition) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	// the payloads are shaped and hashed before locking the positions
+	matched := make([]bool, len(records))
+	hashes := make([]string, len(records))
+	for i := range records {
+		var err error
+		records[i], hashes[i], matched[i], err = c.transform.apply(records[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	c.posMux.Lock()
+	defer c.posMux.Unlock()
+
+	positions := make(map[string]position.EntityPosition, len(c.positions))
+	for name, pos := range c.positions {
+		positions[name] = pos
+	}
+	tagged := make([]sdk.Record, 0, len(records))
+	for i, record := range records {
+		recordPos, err := position.ParsePosition(record.Position)
+		if err != nil {
+			return err
+		}
+		if !matched[i] || c.dedup(entity, recordPos) {
+			if record.Metadata[zendesk.MetadataSnapshotCompleted] == "true" {
+				c.markCompleted[entity] = true
+			}
+			if !matched[i] {
+				// the position moves past the filtered objects, and is persisted with the next record emitted
+				entityPos := update(positions[entity], recordPos)
+				if w, ok := c.windows[entity]; ok {
+					entityPos.Emitted = w.resumable(entityPos)
 				}
+				positions[entity] = entityPos
+			}
+			continue
+		}
+		entityPos := update(positions[entity], recordPos)
+		recordEntityPos := entityPos
+		if w, ok := c.windows[entity]; ok {
+			// the record holds the whole window of its entity, and only the objects needed to resume of the other entities
+			recordEntityPos.Emitted = w.list()
+			entityPos.Emitted = w.resumable(entityPos)
+		}
+		positions[entity] = recordEntityPos
+		record.Position, err = (&position.SourcePosition{Entities: positions}).ToRecordPosition()
+		if err != nil {
+			return err
+		}
+		positions[entity] = entityPos
+
+		s := c.streams[entity]
+		subdomain := s.account.Subdomain
+		if subdomain != "" {
+			// the ids of the accounts can collide
+			record.Key = sdk.RawData(subdomain + ":" + string(record.Key.Bytes()))
+		}
+		if c.transform.changes != nil && !c.transform.changes.Changed(s.entity.Name, string(record.Key.Bytes()), hashes[i], record.Position) {
+			// the tracked fields didn't change, the position moves past the record, and is persisted with the next record emitted
+			if record.Metadata[zendesk.MetadataSnapshotCompleted] == "true" {
+				c.markCompleted[entity] = true
 			}
+			continue
 		}
+
+		metadata := make(map[string]string, len(record.Metadata)+2)
+		for key, val := range record.Metadata {
+			metadata[key] = val
+		}
+		metadata[MetadataEntity] = s.entity.Name
+		if c.markCompleted[entity] {
+			metadata[zendesk.MetadataSnapshotCompleted] = "true"
+			delete(c.markCompleted, entity)
+		}
+		if subdomain != "" {
+			metadata[MetadataSubdomain] = subdomain
+		}
+		record.Metadata = metadata
+		tagged = append(tagged, record)
 	}
+	if len(tagged) == 0 {
+		// every record was dropped, the positions are persisted with the next record emitted
+		c.positions = positions
+		return nil
+	}
+
+	select {
+	case c.caches <- tagged:
+		c.positions = positions
+		return nil
+	case <-c.tomb.Dying():
+		return c.tomb.Err()
+	}
+}
+
+// move sets the position of the entity, once its cursor moved without records,
+// the position is persisted with the next record emitted
+func (c *CDCIterator) move(entity string, pos position.EntityPosition) {
+	c.posMux.Lock()
+	defer c.posMux.Unlock()
+	c.positions[entity] = pos
 }
 
 func (c *CDCIterator) flush() error {
@@ -135,7 +519,12 @@ func (c *CDCIterator) flush() error {
 		select {
 		case <-c.tomb.Dying():
 			return c.tomb.Err()
-		case cache := <-c.caches:
+		case cache, ok := <-c.caches:
+			if !ok {
+				close(c.finished)
+				<-c.tomb.Dying()
+				return c.tomb.Err()
+			}
 			for _, record := range cache {
 				select {
 				case <-c.tomb.Dying():
@@ -18,12 +18,17 @@ package iterator
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/config"
 	"github.com/conduitio/conduit-connector-zendesk/source/iterator/mocks"
 	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk/zendesktest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -36,7 +41,7 @@ func TestNewCDCIterator(t *testing.T) {
 		username      string
 		apiToken      string
 		pollingPeriod time.Duration
-		tp            position.TicketPosition
+		tp            position.EntityPosition
 		isError       bool
 	}{
 		{
@@ -45,14 +50,14 @@ func TestNewCDCIterator(t *testing.T) {
 			username:      "test@testlab.com",
 			apiToken:      "gkdsaj)({jgo43646435#$!ga",
 			pollingPeriod: time.Millisecond,
-			tp:            position.TicketPosition{LastModified: time.Time{}},
+			tp:            position.EntityPosition{LastModified: time.Time{}},
 		}, {
 			name:          "NewCDCIterator with lastModifiedTime=2022-01-02T15:04:05Z",
 			domain:        "testlab",
 			username:      "test@testlab.com",
 			apiToken:      "gkdsaj)({jgo43646435#$!ga",
 			pollingPeriod: time.Millisecond,
-			tp: position.TicketPosition{
+			tp: position.EntityPosition{
 				LastModified: time.Date(2022, 01, 02,
 					15, 04, 05, 0, time.UTC),
 			},
@@ -60,7 +65,7 @@ func TestNewCDCIterator(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res, err := NewCDCIterator(context.Background(), tt.username, tt.apiToken, tt.domain, tt.pollingPeriod, tt.tp)
+			res, err := NewCDCIterator(context.Background(), newAccountClient(t, config.Config{Domain: tt.domain, UserName: tt.username, APIToken: tt.apiToken}), tt.pollingPeriod, []zendesk.Entity{zendesk.Tickets}, true, Options{}, position.SourcePosition{Entities: map[string]position.EntityPosition{zendesk.EntityTickets: tt.tp}})
 			if tt.isError {
 				assert.NotNil(t, err)
 			} else {
@@ -73,7 +78,7 @@ func TestNewCDCIterator(t *testing.T) {
 				if expectedTime < 0 {
 					expectedTime = 0
 				}
-				assert.Equal(t, expectedTime, res.lastModifiedTime.Unix())
+				assert.Equal(t, expectedTime, res.positions[zendesk.EntityTickets].LastModified.Unix())
 			}
 		})
 	}
@@ -83,7 +88,7 @@ func TestFlush(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	dummyPosition, err := (&position.TicketPosition{LastModified: time.Now(), ID: 1234}).ToRecordPosition()
+	dummyPosition, err := (&position.EntityPosition{LastModified: time.Now(), ID: 1234}).ToRecordPosition()
 	assert.NoError(t, err)
 	in := sdk.Record{Position: dummyPosition}
 
@@ -94,7 +99,7 @@ func TestFlush(t *testing.T) {
 
 	out, err := cdc.Next(ctx)
 	assert.NoError(t, err)
-	assert.Equal(t, in, out)
+	assertRecordFromEntity(t, in, out, zendesk.EntityTickets)
 	cdc.Stop()
 	out, err = cdc.Next(ctx)
 	assert.Empty(t, out)
@@ -104,7 +109,7 @@ func TestFlush(t *testing.T) {
 func TestNext(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 
-	dummyPosition, err := (&position.TicketPosition{LastModified: time.Now(), ID: 1234}).ToRecordPosition()
+	dummyPosition, err := (&position.EntityPosition{LastModified: time.Now(), ID: 1234}).ToRecordPosition()
 	assert.NoError(t, err)
 	in := sdk.Record{Position: dummyPosition}
 
@@ -116,7 +121,7 @@ func TestNext(t *testing.T) {
 	out, err := cdc.Next(ctx)
 	mockCursor.AssertExpectations(t)
 	assert.NoError(t, err)
-	assert.Equal(t, in, out)
+	assertRecordFromEntity(t, in, out, zendesk.EntityTickets)
 	cancel()
 	out, err = cdc.Next(ctx)
 	assert.EqualError(t, err, ctx.Err().Error())
@@ -134,7 +139,7 @@ func TestHasNext(t *testing.T) {
 		fn: func(t *testing.T, c *CDCIterator, mc *mocks.ZendeskCursor) {
 			c.mux.Lock()
 			defer c.mux.Unlock()
-			dummyPosition, err := (&position.TicketPosition{LastModified: time.Now(), ID: 1234}).ToRecordPosition()
+			dummyPosition, err := (&position.EntityPosition{LastModified: time.Now(), ID: 1234}).ToRecordPosition()
 			assert.NoError(t, err)
 			in := sdk.Record{Position: dummyPosition}
 			mc.On("FetchRecords", mock.Anything).Return([]sdk.Record{in}, nil)
@@ -189,9 +194,262 @@ func TestStreamIterator_Stop(t *testing.T) {
 	assert.EqualError(t, err, "iterator stopped")
 }
 
+func TestNext_MultipleEntities(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ticketTime := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	ticketPosition, err := (&position.EntityPosition{LastModified: ticketTime, ID: 1}).ToRecordPosition()
+	assert.NoError(t, err)
+	ticket := sdk.Record{Position: ticketPosition, Key: sdk.RawData("1")}
+
+	userTime := time.Date(2022, 5, 9, 5, 49, 55, 0, time.UTC)
+	userPosition, err := (&position.EntityPosition{LastModified: userTime, ID: 2}).ToRecordPosition()
+	assert.NoError(t, err)
+	user := sdk.Record{Position: userPosition, Key: sdk.RawData("2")}
+
+	ticketCursor := new(mocks.ZendeskCursor)
+	ticketCursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{ticket}, nil)
+	ticketCursor.On("FetchRecords", mock.Anything).Return(nil, nil)
+	userCursor := new(mocks.ZendeskCursor)
+	userCursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{user}, nil)
+	userCursor.On("FetchRecords", mock.Anything).Return(nil, nil)
+	orgCursor := new(mocks.ZendeskCursor)
+	orgCursor.On("FetchRecords", mock.Anything).Return(nil, nil)
+
+	orgTime := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	cdc, err := NewCDCIterator(ctx, newAccountClient(t, config.Config{}), 100*time.Millisecond,
+		[]zendesk.Entity{zendesk.Tickets, zendesk.Users, zendesk.Organizations}, true, Options{},
+		position.SourcePosition{Entities: map[string]position.EntityPosition{zendesk.EntityOrganizations: {LastModified: orgTime, ID: 3}}},
+		ticketCursor, userCursor, orgCursor,
+	)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	out, err := cdc.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, zendesk.EntityTickets, out.Metadata[MetadataEntity])
+	sp, err := position.ParseSourcePosition(out.Position)
+	assert.NoError(t, err)
+	assert.Equal(t, ticketTime, sp.Entities[zendesk.EntityTickets].LastModified)
+	assert.Equal(t, orgTime, sp.Entities[zendesk.EntityOrganizations].LastModified)
+	assert.Equal(t, time.Unix(0, 0).Unix(), sp.Entities[zendesk.EntityUsers].LastModified.Unix())
+
+	out, err = cdc.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, zendesk.EntityUsers, out.Metadata[MetadataEntity])
+	sp, err = position.ParseSourcePosition(out.Position)
+	assert.NoError(t, err)
+	assert.Equal(t, ticketTime, sp.Entities[zendesk.EntityTickets].LastModified)
+	assert.Equal(t, userTime, sp.Entities[zendesk.EntityUsers].LastModified)
+	assert.Equal(t, orgTime, sp.Entities[zendesk.EntityOrganizations].LastModified)
+}
+
+// assertRecordFromEntity asserts the record is the input record, tagged with the entity and positioned with the composite position
+func assertRecordFromEntity(t *testing.T, in, out sdk.Record, entity string) {
+	t.Helper()
+	assert.Equal(t, in.Key, out.Key)
+	assert.Equal(t, in.Payload, out.Payload)
+	assert.Equal(t, entity, out.Metadata[MetadataEntity])
+
+	want, err := position.ParsePosition(in.Position)
+	assert.NoError(t, err)
+	got, err := position.ParseSourcePosition(out.Position)
+	assert.NoError(t, err)
+	assert.Equal(t, want.ID, got.Entities[entity].ID)
+	assert.True(t, want.LastModified.Equal(got.Entities[entity].LastModified))
+}
+
 func newTestCDCIterator(ctx context.Context, t *testing.T, pollingPeriod time.Duration, cursors ...ZendeskCursor) *CDCIterator {
 	t.Helper()
-	cdc, err := NewCDCIterator(ctx, "", "", "", pollingPeriod, position.TicketPosition{}, cursors...)
+	cdc, err := NewCDCIterator(ctx, newAccountClient(t, config.Config{}), pollingPeriod, []zendesk.Entity{zendesk.Tickets}, true, Options{}, position.SourcePosition{}, cursors...)
 	assert.NoError(t, err)
 	return cdc
 }
+
+func newAccountClient(t *testing.T, cfg config.Config) *zendesk.Client {
+	t.Helper()
+	client, err := zendesk.NewAccountClient(cfg)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestCDCIterator_Window(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := zendesktest.NewServer()
+	defer server.Close()
+	server.SetPageSize(2)
+	created, err := server.AddTickets(ticketList(0, 10)...)
+	assert.NoError(t, err)
+	start, err := time.Parse(time.RFC3339, created[3]["updated_at"].(string))
+	assert.NoError(t, err)
+	end, err := time.Parse(time.RFC3339, created[7]["updated_at"].(string))
+	assert.NoError(t, err)
+
+	client := zendesk.NewClient(server.URL, zendesk.Retry(3, time.Millisecond))
+	opts := Options{StartTime: start, EndTime: end}
+	cdc, err := NewCDCIterator(ctx, client, 10*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, false, opts, position.SourcePosition{})
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	got := readRecords(ctx, t, cdc, 4)
+	for i, r := range got {
+		assert.Equal(t, strconv.Itoa(4+i), string(r.Key.Bytes()))
+	}
+	for !cdc.Done() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("iterator not done after reading the window")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	assert.False(t, cdc.HasNext(ctx))
+
+	// tickets updated past the end time aren't read
+	addTickets(t, server, 10, 1)
+	readCtx, readCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer readCancel()
+	rec, err := cdc.Next(readCtx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected record %s", rec.Key)
+}
+
+func TestNewCDCIterator_StartTime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	resumed := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+	sp := position.SourcePosition{Entities: map[string]position.EntityPosition{
+		zendesk.EntityUsers: {LastModified: resumed, ID: 12},
+	}}
+	client := zendesk.NewClient("http://localhost")
+	cdc, err := NewCDCIterator(ctx, client, time.Hour, []zendesk.Entity{zendesk.Tickets, zendesk.Users}, false, Options{StartTime: start}, sp)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	// the start time only applies to the entities without position
+	assert.Equal(t, start.Add(-time.Second), cdc.positions[zendesk.EntityTickets].LastModified)
+	assert.Equal(t, resumed, cdc.positions[zendesk.EntityUsers].LastModified)
+}
+
+func TestCDCIterator_Anchored(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	articles := new(mocks.ZendeskCursor)
+	articles.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{{
+		Position: sdk.Position(`{"last_modified_time":"2022-05-08T05:49:55Z","id":1}`),
+		Key:      sdk.RawData("1"),
+		Payload:  sdk.RawData(`{"id":1}`),
+	}}, nil)
+	articles.On("FetchRecords", mock.Anything).Return(nil, nil)
+	tickets := new(mocks.ZendeskCursor)
+	tickets.On("FetchRecords", mock.Anything).Return(nil, nil)
+	cdc, err := NewCDCIterator(ctx, newAccountClient(t, config.Config{}), 10*time.Millisecond,
+		[]zendesk.Entity{zendesk.Articles, zendesk.Tickets}, true, Options{StartTime: start}, position.SourcePosition{}, articles, tickets)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	// the start and snapshot end of the tickets, resolved when opened, are persisted with the records of the articles
+	got := readRecords(ctx, t, cdc, 1)
+	sp, err := position.ParseSourcePosition(got[0].Position)
+	assert.NoError(t, err)
+	pos, ok := sp.Entities[zendesk.EntityTickets]
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(-time.Second), pos.LastModified)
+	assert.NotNil(t, pos.SnapshotEnd)
+}
+
+func TestCDCIterator_Accounts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	ticket := func(updatedAt time.Time) sdk.Record {
+		pos, err := (&position.EntityPosition{LastModified: updatedAt, ID: 1}).ToRecordPosition()
+		assert.NoError(t, err)
+		return sdk.Record{Position: pos, Key: sdk.RawData("1")}
+	}
+	// both accounts have a ticket with the same id
+	acme := new(mocks.ZendeskCursor)
+	acme.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{ticket(updatedAt)}, nil)
+	acme.On("FetchRecords", mock.Anything).Return(nil, nil)
+	acmeEU := new(mocks.ZendeskCursor)
+	acmeEU.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{ticket(updatedAt.Add(time.Second))}, nil)
+	acmeEU.On("FetchRecords", mock.Anything).Return(nil, nil)
+
+	resumed := updatedAt.Add(-time.Hour)
+	sp := position.SourcePosition{Entities: map[string]position.EntityPosition{
+		"acme-eu/tickets": {LastModified: resumed, ID: 7},
+	}}
+	opts := Options{Accounts: []Account{
+		{Subdomain: "acme", Client: newAccountClient(t, config.Config{Domain: "acme"})},
+		{Subdomain: "acme-eu", Client: newAccountClient(t, config.Config{Domain: "acme-eu"})},
+	}}
+	cdc, err := NewCDCIterator(ctx, nil, 10*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, false, opts, sp, acme, acmeEU)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+	assert.Equal(t, []string{"acme/tickets", "acme-eu/tickets"}, cdc.entities)
+	// each account resumes from its own position
+	assert.Equal(t, time.Unix(0, 0), cdc.positions["acme/tickets"].LastModified)
+	assert.Equal(t, resumed, cdc.positions["acme-eu/tickets"].LastModified)
+
+	got := readRecords(ctx, t, cdc, 2)
+	assert.Equal(t, "acme:1", string(got[0].Key.Bytes()))
+	assert.Equal(t, "acme-eu:1", string(got[1].Key.Bytes()))
+	assert.Equal(t, map[string]string{MetadataEntity: zendesk.EntityTickets, MetadataSubdomain: "acme-eu"}, got[1].Metadata)
+
+	last, err := position.ParseSourcePosition(got[1].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, updatedAt, last.Entities["acme/tickets"].LastModified)
+	assert.Equal(t, updatedAt.Add(time.Second), last.Entities["acme-eu/tickets"].LastModified)
+}
+
+// movedCursor is a cursor moving once without records, i.e. a search run completing on an empty page
+type movedCursor struct {
+	pos     position.EntityPosition
+	fetched bool
+}
+
+func (c *movedCursor) FetchRecords(context.Context) ([]sdk.Record, error) {
+	c.fetched = true
+	return nil, nil
+}
+
+func (c *movedCursor) Position() (position.EntityPosition, bool) {
+	return c.pos, c.fetched
+}
+
+func TestCDCIterator_MovedCursor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	search := &movedCursor{pos: position.EntityPosition{LastModified: updatedAt}}
+	// the object of the other account is read once the search run completed
+	acmeEU := new(mocks.ZendeskCursor)
+	acmeEU.On("FetchRecords", mock.Anything).Once().Return(nil, nil)
+	acmeEU.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{{Position: sdk.Position(`{"last_modified_time":"2022-05-08T05:49:55Z","id":1}`), Key: sdk.RawData("1")}}, nil)
+	acmeEU.On("FetchRecords", mock.Anything).Return(nil, nil)
+
+	sp := position.SourcePosition{Entities: map[string]position.EntityPosition{
+		"acme/search": {LastModified: time.Unix(0, 0), ID: 7, Search: &position.SearchPosition{Page: "next"}},
+	}}
+	opts := Options{Accounts: []Account{
+		{Subdomain: "acme", Client: newAccountClient(t, config.Config{Domain: "acme"})},
+		{Subdomain: "acme-eu", Client: newAccountClient(t, config.Config{Domain: "acme-eu"})},
+	}}
+	entity := zendesk.SearchEntity("tags:vip", "ticket")
+	cdc, err := NewCDCIterator(ctx, nil, 10*time.Millisecond, []zendesk.Entity{entity}, false, opts, sp, search, acmeEU)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	// the record of the other account holds the position the search moved to
+	got := readRecords(ctx, t, cdc, 1)
+	last, err := position.ParseSourcePosition(got[0].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, position.EntityPosition{LastModified: updatedAt}, last.Entities["acme/search"])
+}
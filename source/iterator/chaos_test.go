@@ -0,0 +1,154 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk/zendesktest"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCDCIterator_Faults reads tickets while zendesk misbehaves, restarting the iterator from the position of the last read record
+// half way through, and asserts every ticket is read exactly once, in order, with a unique position
+func TestCDCIterator_Faults(t *testing.T) {
+	tests := []struct {
+		name         string
+		faults       []zendesktest.Fault
+		resumeFaults []zendesktest.Fault // injected before resuming the iterator
+	}{
+		{
+			name:   "server error storm",
+			faults: []zendesktest.Fault{{Kind: zendesktest.FaultServerError, StatusCode: 503, Times: 10}},
+		},
+		{
+			name:   "truncated json",
+			faults: []zendesktest.Fault{{Kind: zendesktest.FaultTruncatedJSON, Times: 3}},
+		},
+		{
+			name:   "slow responses beyond the timeout",
+			faults: []zendesktest.Fault{{Kind: zendesktest.FaultSlowResponse, Delay: 300 * time.Millisecond, Times: 6}},
+		},
+		{
+			name:   "expired after url",
+			faults: []zendesktest.Fault{{Kind: zendesktest.FaultExpiredCursor, Times: 3}},
+		},
+		{
+			name:   "duplicate tickets across pages",
+			faults: []zendesktest.Fault{{Kind: zendesktest.FaultDuplicateTickets}},
+		},
+		{
+			// the position of a record without timestamps falls back to the last valid modified time,
+			// so the iterator isn't restarted right after such records, as they would be read again
+			name:         "zero timestamps",
+			resumeFaults: []zendesktest.Fault{{Kind: zendesktest.FaultZeroTimestamps, Times: 1}},
+		},
+		{
+			name: "mixed faults",
+			faults: []zendesktest.Fault{
+				{Kind: zendesktest.FaultServerError, Times: 2},
+				{Kind: zendesktest.FaultTruncatedJSON, Times: 1},
+				{Kind: zendesktest.FaultExpiredCursor, Times: 1},
+				{Kind: zendesktest.FaultDuplicateTickets, Times: 3},
+				{Kind: zendesktest.FaultSlowResponse, Delay: 300 * time.Millisecond, Times: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+
+			server := zendesktest.NewServer()
+			defer server.Close()
+			server.SetPageSize(3)
+			addTickets(t, server, 0, 10)
+			server.Inject(tt.faults...)
+
+			client := zendesk.NewClient(server.URL, zendesk.Retry(3, time.Millisecond), zendesk.Timeout(100*time.Millisecond))
+
+			// snapshot, interrupted after 6 records
+			cdc, err := NewCDCIterator(ctx, client, 10*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, true, Options{}, position.SourcePosition{})
+			assert.NoError(t, err)
+			got := readRecords(ctx, t, cdc, 6)
+			cdc.Stop()
+
+			// resume from the position of the last read record
+			server.Inject(tt.resumeFaults...)
+			sp, err := position.ParseSourcePosition(got[len(got)-1].Position)
+			assert.NoError(t, err)
+			cdc, err = NewCDCIterator(ctx, client, 10*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, true, Options{}, sp)
+			assert.NoError(t, err)
+			defer cdc.Stop()
+			got = append(got, readRecords(ctx, t, cdc, 4)...)
+
+			// CDC, the tickets created while the iterator is running
+			addTickets(t, server, 10, 5)
+			got = append(got, readRecords(ctx, t, cdc, 5)...)
+
+			// no record is read again
+			readCtx, readCancel := context.WithTimeout(ctx, 300*time.Millisecond)
+			defer readCancel()
+			rec, err := cdc.Next(readCtx)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected record %s", rec.Key)
+
+			keys := make([]string, 0, len(got))
+			positions := make(map[string]bool, len(got))
+			for _, r := range got {
+				keys = append(keys, string(r.Key.Bytes()))
+				assert.False(t, positions[string(r.Position)], "duplicate position %s", r.Position)
+				positions[string(r.Position)] = true
+			}
+			assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15"}, keys)
+		})
+	}
+}
+
+func addTickets(t *testing.T, server *zendesktest.Server, from, count int) {
+	t.Helper()
+	_, err := server.AddTickets(ticketList(from, count)...)
+	assert.NoError(t, err)
+}
+
+func ticketList(from, count int) []map[string]interface{} {
+	tickets := make([]map[string]interface{}, 0, count)
+	for i := from; i < from+count; i++ {
+		tickets = append(tickets, map[string]interface{}{"subject": fmt.Sprintf("ticket %d", i)})
+	}
+	return tickets
+}
+
+func readRecords(ctx context.Context, t *testing.T, cdc *CDCIterator, n int) []sdk.Record {
+	t.Helper()
+	records := make([]sdk.Record, 0, n)
+	for len(records) < n {
+		rec, err := cdc.Next(ctx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		records = append(records, rec)
+	}
+	return records
+}
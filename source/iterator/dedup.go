@@ -0,0 +1,121 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+)
+
+// window is the dedup window of an entity, the objects of the last records emitted, updated in place
+type window struct {
+	objects []position.EmittedObject // ring buffer of the emitted objects
+	next    int                      // index the next object is written at, the oldest object once the ring is full
+	seen    map[emittedKey]struct{}  // objects of the ring
+}
+
+// emittedKey is the comparable form of an emitted object
+type emittedKey struct {
+	id        float64
+	updatedAt int64
+}
+
+func keyOf(id float64, updatedAt time.Time) emittedKey {
+	return emittedKey{id: id, updatedAt: updatedAt.UnixNano()}
+}
+
+// newWindow returns the window of the size, holding the last objects emitted, oldest first
+func newWindow(size int, emitted []position.EmittedObject) *window {
+	w := &window{
+		objects: make([]position.EmittedObject, 0, size),
+		seen:    make(map[emittedKey]struct{}, size),
+	}
+	for _, object := range emitted {
+		w.add(object)
+	}
+	return w
+}
+
+// add adds the object to the window, in place of the oldest object once the window is full
+func (w *window) add(object position.EmittedObject) {
+	if len(w.objects) < cap(w.objects) {
+		w.objects = append(w.objects, object)
+	} else {
+		delete(w.seen, keyOf(w.objects[w.next].ID, w.objects[w.next].UpdatedAt))
+		w.objects[w.next] = object
+		w.next = (w.next + 1) % len(w.objects)
+	}
+	w.seen[keyOf(object.ID, object.UpdatedAt)] = struct{}{}
+}
+
+// contains reports whether the object was emitted with the update time
+func (w *window) contains(id float64, updatedAt time.Time) bool {
+	_, ok := w.seen[keyOf(id, updatedAt)]
+	return ok
+}
+
+// list returns the objects of the window, oldest first
+func (w *window) list() []position.EmittedObject {
+	list := make([]position.EmittedObject, 0, len(w.objects))
+	list = append(list, w.objects[w.next:]...)
+	return append(list, w.objects[:w.next]...)
+}
+
+// resumable returns the objects of the window emitted at the last modified time of the position, or of its backfill slices,
+// which are skipped once the entity resumes from that time. The records of the other entities only hold these objects.
+func (w *window) resumable(pos position.EntityPosition) []position.EmittedObject {
+	var objects []position.EmittedObject
+	for _, object := range w.list() {
+		if object.UpdatedAt.Equal(pos.LastModified) {
+			objects = append(objects, object)
+			continue
+		}
+		for _, slice := range pos.Backfill {
+			if object.UpdatedAt.Equal(slice.LastModified) {
+				objects = append(objects, object)
+				break
+			}
+		}
+	}
+	return objects
+}
+
+// dedup reports whether the object of the record position was emitted with the same update time, according to the window
+// of the entity, the object is added to the window otherwise, dropping the oldest object once the window is full
+func (c *CDCIterator) dedup(entity string, pos position.EntityPosition) bool {
+	w, ok := c.windows[entity]
+	if !ok {
+		return false
+	}
+	if w.contains(pos.ID, pos.LastModified) {
+		return true
+	}
+	w.add(position.EmittedObject{ID: pos.ID, UpdatedAt: pos.LastModified})
+	return false
+}
+
+// emittedAt returns the ids of the objects of the window emitted with the update time
+func emittedAt(window []position.EmittedObject, updatedAt time.Time) []float64 {
+	var ids []float64
+	for _, object := range window {
+		if object.UpdatedAt.Equal(updatedAt) {
+			ids = append(ids, object.ID)
+		}
+	}
+	return ids
+}
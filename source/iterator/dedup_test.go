@@ -0,0 +1,133 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/conduitio/conduit-connector-zendesk/source/iterator/mocks"
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCDCIterator_Dedup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	ticket := func(id float64, updatedAt time.Time) sdk.Record {
+		pos, err := (&position.EntityPosition{LastModified: updatedAt, ID: id}).ToRecordPosition()
+		assert.NoError(t, err)
+		return sdk.Record{Position: pos, Key: sdk.RawData(fmt.Sprintf("%v", id))}
+	}
+	a, b, c := ticket(1, updatedAt), ticket(2, updatedAt), ticket(3, updatedAt.Add(time.Second))
+
+	// the same ticket version is returned again
+	cursor := new(mocks.ZendeskCursor)
+	cursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{a, b}, nil)
+	cursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{b, c}, nil)
+	cursor.On("FetchRecords", mock.Anything).Return(nil, nil)
+	opts := Options{DedupWindow: 2}
+	cdc, err := NewCDCIterator(ctx, newAccountClient(t, config.Config{}), 10*time.Millisecond,
+		[]zendesk.Entity{zendesk.Tickets}, false, opts, position.SourcePosition{}, cursor)
+	assert.NoError(t, err)
+	got := readRecords(ctx, t, cdc, 3)
+	cdc.Stop()
+	assert.Equal(t, []string{"1", "2", "3"}, []string{string(got[0].Key.Bytes()), string(got[1].Key.Bytes()), string(got[2].Key.Bytes())})
+
+	// the window is persisted in the position, oldest objects first
+	sp, err := position.ParseSourcePosition(got[2].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, []position.EmittedObject{{ID: 2, UpdatedAt: updatedAt}, {ID: 3, UpdatedAt: updatedAt.Add(time.Second)}},
+		sp.Entities[zendesk.EntityTickets].Emitted)
+
+	// the tickets emitted before the restart are dropped
+	d := ticket(4, updatedAt.Add(2*time.Second))
+	cursor = new(mocks.ZendeskCursor)
+	cursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{b, c, d}, nil)
+	cursor.On("FetchRecords", mock.Anything).Return(nil, nil)
+	cdc, err = NewCDCIterator(ctx, newAccountClient(t, config.Config{}), 10*time.Millisecond,
+		[]zendesk.Entity{zendesk.Tickets}, false, opts, sp, cursor)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+	got = readRecords(ctx, t, cdc, 1)
+	assert.Equal(t, "4", string(got[0].Key.Bytes()))
+}
+
+func TestCDCIterator_DedupPositions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	object := func(id float64, updatedAt time.Time) sdk.Record {
+		pos, err := (&position.EntityPosition{LastModified: updatedAt, ID: id}).ToRecordPosition()
+		assert.NoError(t, err)
+		return sdk.Record{Position: pos, Key: sdk.RawData(fmt.Sprintf("%v", id))}
+	}
+
+	tickets := new(mocks.ZendeskCursor)
+	tickets.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{object(1, updatedAt), object(2, updatedAt.Add(time.Second))}, nil)
+	tickets.On("FetchRecords", mock.Anything).Return(nil, nil)
+	articles := new(mocks.ZendeskCursor)
+	articles.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{object(3, updatedAt)}, nil)
+	articles.On("FetchRecords", mock.Anything).Return(nil, nil)
+	cdc, err := NewCDCIterator(ctx, newAccountClient(t, config.Config{}), 10*time.Millisecond,
+		[]zendesk.Entity{zendesk.Tickets, zendesk.Articles}, false, Options{DedupWindow: 3}, position.SourcePosition{}, tickets, articles)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+	got := readRecords(ctx, t, cdc, 3)
+
+	// the position of the article holds its whole window, and only the ticket emitted at the last modified time of the tickets
+	sp, err := position.ParseSourcePosition(got[2].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, []position.EmittedObject{{ID: 2, UpdatedAt: updatedAt.Add(time.Second)}}, sp.Entities[zendesk.EntityTickets].Emitted)
+	assert.Equal(t, []position.EmittedObject{{ID: 3, UpdatedAt: updatedAt}}, sp.Entities[zendesk.EntityArticles].Emitted)
+}
+
+func TestWindow(t *testing.T) {
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	w := newWindow(2, []position.EmittedObject{{ID: 1, UpdatedAt: updatedAt}})
+	assert.True(t, w.contains(1, updatedAt))
+	assert.False(t, w.contains(1, updatedAt.Add(time.Second)))
+
+	// the oldest object is replaced once the window is full
+	w.add(position.EmittedObject{ID: 2, UpdatedAt: updatedAt})
+	w.add(position.EmittedObject{ID: 3, UpdatedAt: updatedAt.Add(time.Second)})
+	assert.False(t, w.contains(1, updatedAt))
+	assert.Equal(t, []position.EmittedObject{{ID: 2, UpdatedAt: updatedAt}, {ID: 3, UpdatedAt: updatedAt.Add(time.Second)}}, w.list())
+
+	assert.Equal(t, []position.EmittedObject{{ID: 3, UpdatedAt: updatedAt.Add(time.Second)}},
+		w.resumable(position.EntityPosition{LastModified: updatedAt.Add(time.Second)}))
+	assert.Equal(t, w.list(), w.resumable(position.EntityPosition{
+		LastModified: updatedAt.Add(time.Second),
+		Backfill:     []position.SlicePosition{{LastModified: updatedAt}},
+	}))
+}
+
+func TestEmittedAt(t *testing.T) {
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	window := []position.EmittedObject{{ID: 1, UpdatedAt: updatedAt.Add(-time.Second)}, {ID: 2, UpdatedAt: updatedAt}, {ID: 3, UpdatedAt: updatedAt}}
+	assert.Equal(t, []float64{2, 3}, emittedAt(window, updatedAt))
+	assert.Nil(t, emittedAt(nil, updatedAt))
+}
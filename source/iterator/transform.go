@@ -0,0 +1,92 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/source/changes"
+	"github.com/conduitio/conduit-connector-zendesk/source/filter"
+	"github.com/conduitio/conduit-connector-zendesk/source/projection"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+)
+
+// transform filters the records read by the cursors, and shapes their payloads
+type transform struct {
+	filter       *filter.Filter         // records of the objects not matching the filter are dropped, nil to keep every record
+	customFields *zendesk.CustomFields  // flattening of the custom fields of the payloads, nil to keep them as is
+	projection   *projection.Projection // fields selection and redaction of the payloads, nil to keep every field
+	changes      *changes.Detector      // hashing of the tracked fields of the payloads, nil to not hash them
+}
+
+func newTransform(opts Options) transform {
+	return transform{filter: opts.Filter, customFields: opts.CustomFields, projection: opts.Projection, changes: opts.Changes}
+}
+
+// apply returns the record with its payload flattened and projected, the hash of the tracked fields of its flattened payload,
+// before the projection, and false if its object doesn't match the filter.
+// The other fields of the record are set by the cursors from the original object.
+func (t transform) apply(record sdk.Record) (sdk.Record, string, bool, error) {
+	if t.filter == nil && t.customFields == nil && t.projection == nil && t.changes == nil {
+		return record, "", true, nil
+	}
+
+	var object map[string]interface{}
+	err := json.Unmarshal(record.Payload.Bytes(), &object)
+	if err != nil {
+		return sdk.Record{}, "", false, fmt.Errorf("error unmarshaling the payload: %w", err)
+	}
+	if t.filter != nil && !t.filter.Match(object) {
+		return record, "", false, nil
+	}
+	if t.customFields != nil {
+		object = t.customFields.Flatten(object)
+	}
+
+	var payload []byte
+	var hash string
+	if t.changes != nil {
+		// the tracked fields are hashed before the projection drops or redacts them
+		payload, err = json.Marshal(object)
+		if err != nil {
+			return sdk.Record{}, "", false, fmt.Errorf("error marshaling the payload: %w", err)
+		}
+		hash, err = t.changes.Hash(payload)
+		if err != nil {
+			return sdk.Record{}, "", false, err
+		}
+	}
+	if t.projection == nil && payload != nil {
+		record.Payload = sdk.RawData(payload)
+		return record, hash, true, nil
+	}
+	if t.projection != nil {
+		object, err = t.projection.Apply(object)
+		if err != nil {
+			return sdk.Record{}, "", false, fmt.Errorf("error projecting the payload: %w", err)
+		}
+	}
+
+	payload, err = json.Marshal(object)
+	if err != nil {
+		return sdk.Record{}, "", false, fmt.Errorf("error marshaling the payload: %w", err)
+	}
+	record.Payload = sdk.RawData(payload)
+	return record, hash, true, nil
+}
@@ -0,0 +1,228 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/conduitio/conduit-connector-zendesk/source/changes"
+	"github.com/conduitio/conduit-connector-zendesk/source/filter"
+	"github.com/conduitio/conduit-connector-zendesk/source/iterator/mocks"
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/source/projection"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk/zendesktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTransform_apply(t *testing.T) {
+	f, err := filter.Parse(`status != "closed"`)
+	assert.NoError(t, err)
+	p, err := projection.New([]string{"id", "requester", "custom_fields"}, nil, []projection.Rule{{Path: "requester.email", Action: projection.ActionMask}}, "")
+	assert.NoError(t, err)
+	fields := []zendesk.TicketField{{ID: 360001, Type: "integer", Title: "Seats", Removable: true}}
+	tr := transform{filter: f, projection: p, customFields: zendesk.NewCustomFields(func() []zendesk.TicketField { return fields }, nil)}
+
+	record := sdk.Record{
+		Key:      sdk.RawData("1"),
+		Metadata: map[string]string{zendesk.MetadataSnapshot: "true"},
+		Payload: sdk.RawData(`{"id":1,"status":"open","requester":{"email":"jdoe@example.com","name":"John Doe"},` +
+			`"custom_fields":[{"id":360001,"value":"12"}]}`),
+	}
+	got, hash, ok, err := tr.apply(record)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, hash)
+	assert.JSONEq(t, `{"id":1,"requester":{"email":"****","name":"John Doe"},"custom_fields":{"Seats":12}}`, string(got.Payload.Bytes()))
+	// the other fields are set by the cursor from the original object
+	assert.Equal(t, record.Key, got.Key)
+	assert.Equal(t, record.Metadata, got.Metadata)
+
+	_, _, ok, err = tr.apply(sdk.Record{Payload: sdk.RawData(`{"id":2,"status":"closed"}`)})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, _, err = tr.apply(sdk.Record{Payload: sdk.RawData(`{"id":`)})
+	assert.Error(t, err)
+}
+
+func TestTransform_apply_Changes(t *testing.T) {
+	d, err := changes.New([]string{"requester.email", "custom_fields.Seats"}, filepath.Join(t.TempDir(), "changes.log"))
+	assert.NoError(t, err)
+	defer d.Close()
+	p, err := projection.New([]string{"id", "requester"}, nil, []projection.Rule{{Path: "requester.email", Action: projection.ActionMask}}, "")
+	assert.NoError(t, err)
+	fields := []zendesk.TicketField{{ID: 360001, Type: "integer", Title: "Seats", Removable: true}}
+	tr := transform{projection: p, changes: d, customFields: zendesk.NewCustomFields(func() []zendesk.TicketField { return fields }, nil)}
+
+	// the tracked fields are hashed once flattened, before the projection masks or drops them
+	apply := func(email, seats string) (sdk.Record, string) {
+		t.Helper()
+		got, hash, ok, err := tr.apply(sdk.Record{Payload: sdk.RawData(fmt.Sprintf(
+			`{"id":1,"requester":{"email":%q},"custom_fields":[{"id":360001,"value":%q}]}`, email, seats))})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		return got, hash
+	}
+	got, hash := apply("jdoe@example.com", "12")
+	assert.JSONEq(t, `{"id":1,"requester":{"email":"****"}}`, string(got.Payload.Bytes()))
+	want, err := d.Hash([]byte(`{"requester":{"email":"jdoe@example.com"},"custom_fields":{"Seats":12}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, want, hash)
+
+	_, other := apply("john@example.com", "12")
+	assert.NotEqual(t, hash, other)
+	_, other = apply("jdoe@example.com", "13")
+	assert.NotEqual(t, hash, other)
+}
+
+func TestCDCIterator_Changes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	ticket := func(id float64, second int, email string, metadata map[string]string) sdk.Record {
+		pos, err := (&position.EntityPosition{LastModified: updatedAt.Add(time.Duration(second) * time.Second), ID: id}).ToRecordPosition()
+		assert.NoError(t, err)
+		return sdk.Record{
+			Position: pos,
+			Metadata: metadata,
+			Key:      sdk.RawData(fmt.Sprintf("%v", id)),
+			Payload:  sdk.RawData(fmt.Sprintf(`{"id":%v,"requester":{"email":%q}}`, id, email)),
+		}
+	}
+
+	// the snapshot completes on an unchanged ticket, the email is dropped by the projection
+	cursor := new(mocks.ZendeskCursor)
+	cursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{
+		ticket(1, 1, "jdoe@example.com", map[string]string{zendesk.MetadataSnapshot: "true"}),
+		ticket(1, 2, "jdoe@example.com", map[string]string{zendesk.MetadataSnapshot: "true", zendesk.MetadataSnapshotCompleted: "true"}),
+	}, nil)
+	cursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{ticket(1, 3, "john@example.com", nil)}, nil)
+	cursor.On("FetchRecords", mock.Anything).Return(nil, nil)
+	d, err := changes.New([]string{"requester.email"}, filepath.Join(t.TempDir(), "changes.log"))
+	assert.NoError(t, err)
+	defer d.Close()
+	p, err := projection.New(nil, []string{"requester"}, nil, "")
+	assert.NoError(t, err)
+	cdc, err := NewCDCIterator(ctx, newAccountClient(t, config.Config{}), 10*time.Millisecond,
+		[]zendesk.Entity{zendesk.Tickets}, false, Options{Projection: p, Changes: d}, position.SourcePosition{}, cursor)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	got := readRecords(ctx, t, cdc, 2)
+	assert.JSONEq(t, `{"id":1}`, string(got[0].Payload.Bytes()))
+	assert.Empty(t, got[0].Metadata[zendesk.MetadataSnapshotCompleted])
+	// the change of the dropped email is emitted, and marks the completion of the snapshot on the unchanged record
+	assert.JSONEq(t, `{"id":1}`, string(got[1].Payload.Bytes()))
+	assert.Equal(t, "true", got[1].Metadata[zendesk.MetadataSnapshotCompleted])
+}
+
+func TestCDCIterator_Filter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updatedAt := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	ticket := func(id float64, status string, metadata map[string]string) sdk.Record {
+		pos, err := (&position.EntityPosition{LastModified: updatedAt.Add(time.Duration(id) * time.Second), ID: id}).ToRecordPosition()
+		assert.NoError(t, err)
+		return sdk.Record{
+			Position: pos,
+			Metadata: metadata,
+			Key:      sdk.RawData(fmt.Sprintf("%v", id)),
+			Payload:  sdk.RawData(fmt.Sprintf(`{"id":%v,"status":%q}`, id, status)),
+		}
+	}
+
+	// the snapshot completes on a dropped ticket
+	cursor := new(mocks.ZendeskCursor)
+	cursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{
+		ticket(1, "open", map[string]string{zendesk.MetadataSnapshot: "true"}),
+		ticket(2, "closed", map[string]string{zendesk.MetadataSnapshot: "true", zendesk.MetadataSnapshotCompleted: "true"}),
+	}, nil)
+	cursor.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{ticket(3, "closed", nil), ticket(4, "open", nil)}, nil)
+	cursor.On("FetchRecords", mock.Anything).Return(nil, nil)
+	f, err := filter.Parse(`status != "closed"`)
+	assert.NoError(t, err)
+	cdc, err := NewCDCIterator(ctx, newAccountClient(t, config.Config{}), 10*time.Millisecond,
+		[]zendesk.Entity{zendesk.Tickets}, false, Options{Filter: f}, position.SourcePosition{}, cursor)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	got := readRecords(ctx, t, cdc, 2)
+	assert.Equal(t, "1", string(got[0].Key.Bytes()))
+	assert.Empty(t, got[0].Metadata[zendesk.MetadataSnapshotCompleted])
+	// the snapshot completion is marked on the next record matching the filter
+	assert.Equal(t, "4", string(got[1].Key.Bytes()))
+	assert.Equal(t, "true", got[1].Metadata[zendesk.MetadataSnapshotCompleted])
+}
+
+func TestCDCIterator_FilterRestart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := zendesktest.NewServer()
+	defer server.Close()
+	server.SetPageSize(2)
+	// the tickets read after the open one span 2 pages, and are all dropped
+	tickets := []map[string]interface{}{{"status": "open"}}
+	for i := 0; i < 4; i++ {
+		tickets = append(tickets, map[string]interface{}{"status": "closed"})
+	}
+	created, err := server.AddTickets(tickets...)
+	assert.NoError(t, err)
+	f, err := filter.Parse(`status != "closed"`)
+	assert.NoError(t, err)
+	opts := Options{Filter: f}
+	client := zendesk.NewClient(server.URL)
+
+	// the article is read once the tickets are
+	articles := new(mocks.ZendeskCursor)
+	articles.On("FetchRecords", mock.Anything).Times(3).Return(nil, nil)
+	articles.On("FetchRecords", mock.Anything).Once().Return([]sdk.Record{{Position: sdk.Position(`{"last_modified_time":"2022-05-08T05:49:55Z","id":1}`), Key: sdk.RawData("1"), Payload: sdk.RawData(`{"id":1}`)}}, nil)
+	articles.On("FetchRecords", mock.Anything).Return(nil, nil)
+	cdc, err := NewCDCIterator(ctx, client, 10*time.Millisecond, []zendesk.Entity{zendesk.Articles, zendesk.Tickets}, false, opts, position.SourcePosition{}, articles)
+	assert.NoError(t, err)
+	got := readRecords(ctx, t, cdc, 2)
+	cdc.Stop()
+	_ = cdc.tomb.Wait()
+	assert.Equal(t, zendesk.EntityTickets, got[0].Metadata[MetadataEntity])
+	assert.Equal(t, zendesk.EntityArticles, got[1].Metadata[MetadataEntity])
+
+	// the record of the article holds the position of the tickets past the dropped ones
+	sp, err := position.ParseSourcePosition(got[1].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, created[4]["updated_at"], sp.Entities[zendesk.EntityTickets].LastModified.Format(time.RFC3339))
+
+	// after a restart, the export resumes past the dropped tickets
+	_, err = server.AddTickets(map[string]interface{}{"status": "open"})
+	assert.NoError(t, err)
+	requests := server.RequestCount(zendesktest.TicketsExportPath)
+	cdc, err = NewCDCIterator(ctx, client, 200*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, false, opts, sp)
+	assert.NoError(t, err)
+	defer cdc.Stop()
+	got = readRecords(ctx, t, cdc, 1)
+	assert.Equal(t, "6", string(got[0].Key.Bytes()))
+	assert.Equal(t, requests+1, server.RequestCount(zendesktest.TicketsExportPath))
+}
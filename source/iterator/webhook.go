@@ -0,0 +1,111 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"context"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/source/webhook"
+)
+
+// receive pushes the records of the objects received by the webhook. They don't move the position of their entity,
+// which is only moved by the export reconciling the objects missed by the webhook.
+func (c *CDCIterator) receive(ctx context.Context, events <-chan webhook.Event) func() error {
+	return func() error {
+		for {
+			select {
+			case <-c.tomb.Dying():
+				return c.tomb.Err()
+			case event := <-events:
+				if err := c.pushEvent(ctx, event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (c *CDCIterator) pushEvent(ctx context.Context, event webhook.Event) error {
+	converter, ok := c.converters[event.Entity]
+	if !ok {
+		return nil
+	}
+	record, err := converter.Record(event.Object)
+	if err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Str("entity", event.Entity).Msg("dropping the object received by the webhook")
+		return nil
+	}
+	recordPos, err := position.ParsePosition(record.Position)
+	if err != nil {
+		return err
+	}
+
+	if record.Metadata == nil {
+		record.Metadata = make(map[string]string)
+	}
+	record.Metadata[MetadataWebhook] = "true"
+	c.markDelivered(event.Entity, recordPos)
+	return c.push(event.Entity, []sdk.Record{record}, func(pos, _ position.EntityPosition) position.EntityPosition {
+		return pos
+	})
+}
+
+// markDelivered records the update time of the object received by the webhook, so the export skips it
+func (c *CDCIterator) markDelivered(entity string, pos position.EntityPosition) {
+	c.deliveredMux.Lock()
+	defer c.deliveredMux.Unlock()
+	if c.delivered[entity] == nil {
+		c.delivered[entity] = make(map[float64]time.Time)
+	}
+	if pos.LastModified.After(c.delivered[entity][pos.ID]) {
+		c.delivered[entity][pos.ID] = pos.LastModified
+	}
+}
+
+// skipDelivered drops the exported records of the objects already received by the webhook, with the same update time,
+// and forgets the objects reached by the export
+func (c *CDCIterator) skipDelivered(entity string, records []sdk.Record) ([]sdk.Record, error) {
+	c.deliveredMux.Lock()
+	defer c.deliveredMux.Unlock()
+	delivered := c.delivered[entity]
+	if len(delivered) == 0 {
+		return records, nil
+	}
+
+	kept := records[:0]
+	for _, record := range records {
+		recordPos, err := position.ParsePosition(record.Position)
+		if err != nil {
+			return nil, err
+		}
+		deliveredAt, ok := delivered[recordPos.ID]
+		if !ok {
+			kept = append(kept, record)
+			continue
+		}
+		if !recordPos.LastModified.Before(deliveredAt) {
+			delete(delivered, recordPos.ID)
+		}
+		if recordPos.LastModified.After(deliveredAt) {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iterator
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/source/webhook"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk/zendesktest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCDCIterator_Webhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := zendesktest.NewServer()
+	defer server.Close()
+	client := zendesk.NewClient(server.URL, zendesk.Retry(3, time.Millisecond))
+	events := make(chan webhook.Event)
+	cdc, err := NewCDCIterator(ctx, client, 200*time.Millisecond, []zendesk.Entity{zendesk.Tickets}, false, Options{Events: events}, position.SourcePosition{})
+	assert.NoError(t, err)
+	defer cdc.Stop()
+	// the first sweep runs right away
+	assert.Eventually(t, func() bool {
+		return server.RequestCount(zendesktest.TicketsExportPath) == 1
+	}, time.Second, time.Millisecond)
+
+	// the ticket is received before the next sweep
+	created, err := server.AddTickets(ticketList(0, 2)...)
+	assert.NoError(t, err)
+	events <- webhook.Event{Entity: zendesk.EntityTickets, Object: created[0]}
+	rec := readRecords(ctx, t, cdc, 1)[0]
+	assert.Equal(t, "1", string(rec.Key.Bytes()))
+	assert.Equal(t, "true", rec.Metadata[MetadataWebhook])
+	sp, err := position.ParseSourcePosition(rec.Position)
+	assert.NoError(t, err)
+	// the position only moves with the export
+	assert.True(t, sp.Entities[zendesk.EntityTickets].LastModified.Equal(time.Unix(0, 0)))
+
+	// the sweep skips the received ticket, and reconciles the missed one
+	rec = readRecords(ctx, t, cdc, 1)[0]
+	assert.Equal(t, "2", string(rec.Key.Bytes()))
+	assert.Empty(t, rec.Metadata[MetadataWebhook])
+
+	readCtx, readCancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer readCancel()
+	rec, err = cdc.Next(readCtx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected record %s", rec.Key)
+	cdc.deliveredMux.Lock()
+	defer cdc.deliveredMux.Unlock()
+	assert.Empty(t, cdc.delivered[zendesk.EntityTickets])
+}
+
+func TestCDCIterator_WebhookSweepPages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := zendesktest.NewServer()
+	defer server.Close()
+	server.SetPageSize(2)
+	// the tickets missed while the webhook wasn't running span 3 pages of the export
+	_, err := server.AddTickets(ticketList(0, 5)...)
+	assert.NoError(t, err)
+
+	client := zendesk.NewClient(server.URL, zendesk.Retry(3, time.Millisecond))
+	events := make(chan webhook.Event)
+	cdc, err := NewCDCIterator(ctx, client, time.Hour, []zendesk.Entity{zendesk.Tickets}, false, Options{Events: events}, position.SourcePosition{})
+	assert.NoError(t, err)
+	defer cdc.Stop()
+
+	// every page is read by the first sweep, without waiting for the sweep period
+	records := readRecords(ctx, t, cdc, 5)
+	for i, rec := range records {
+		assert.Equal(t, strconv.Itoa(i+1), string(rec.Key.Bytes()))
+	}
+	assert.Equal(t, 3, server.RequestCount(zendesktest.TicketsExportPath))
+}
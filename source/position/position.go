@@ -24,13 +24,64 @@ import (
 	sdk "github.com/conduitio/conduit-connector-sdk"
 )
 
-type TicketPosition struct {
+// legacyEntity is the entity to which positions without entity information belong
+const legacyEntity = "tickets"
+
+// EntityPosition is the position of a zendesk entity object, i.e. ticket, satisfaction rating, article etc.
+type EntityPosition struct {
 	LastModified time.Time `json:"last_modified_time"`
-	ID           float64   `json:"id"` // two tickets can have the same update time, id is to keep the position unique across tickets
+	ID           float64   `json:"id"` // two objects can have the same update time, id is to keep the position unique across objects
+	// SnapshotEnd is the time at which the snapshot of the entity started, set only till the snapshot completes
+	SnapshotEnd *time.Time `json:"snapshot_end,omitempty"`
+	// Backfill is the progress of the slices of the backfill, set only till the backfill completes
+	Backfill []SlicePosition `json:"backfill,omitempty"`
+	// Emitted is the dedup window, the objects of the last records emitted, oldest first, set only when deduplicating
+	Emitted []EmittedObject `json:"emitted,omitempty"`
+	// Search is the progress of the run of the search query started from the last modified time, set only till the run completes
+	Search *SearchPosition `json:"search,omitempty"`
+}
+
+// SearchPosition is the progress of a run of the search query, at the object of the id
+type SearchPosition struct {
+	Page            string    `json:"page,omitempty"`         // url of the page of the run the object was read from, empty for the first page
+	RunLastModified time.Time `json:"run_last_modified_time"` // latest update time of the results read by the run till the object
+}
+
+// EmittedObject identifies the version of an object emitted as a record
+type EmittedObject struct {
+	ID        float64   `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SlicePosition is the progress of a backfill slice, reading the objects updated from Start till End, excluded
+type SlicePosition struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	LastModified time.Time `json:"last_modified_time"` // last modified time of the last object read, zero till the first one
+	ID           float64   `json:"id,omitempty"`
+	Done         bool      `json:"done,omitempty"`
+}
+
+// ToRecordPosition will marshal the EntityPosition to sdk.Position
+func (pos *EntityPosition) ToRecordPosition() (sdk.Position, error) {
+	res, err := json.Marshal(pos)
+	if err != nil {
+		return sdk.Position{}, fmt.Errorf("error in parsing the position %w", err)
+	}
+
+	return res, nil
+}
+
+// SourcePosition is the position of the source, holding the position of every entity being read,
+// so each entity can resume independently
+type SourcePosition struct {
+	Entities map[string]EntityPosition `json:"entities"`
+	// Schema is the fingerprint of the last schema of the tickets emitted, set only when inferring the schema
+	Schema string `json:"schema,omitempty"`
 }
 
-// ToRecordPosition will extract the after_url from the ticket result json
-func (pos *TicketPosition) ToRecordPosition() (sdk.Position, error) {
+// ToRecordPosition will marshal the SourcePosition to sdk.Position
+func (pos *SourcePosition) ToRecordPosition() (sdk.Position, error) {
 	res, err := json.Marshal(pos)
 	if err != nil {
 		return sdk.Position{}, fmt.Errorf("error in parsing the position %w", err)
@@ -39,19 +90,43 @@ func (pos *TicketPosition) ToRecordPosition() (sdk.Position, error) {
 	return res, nil
 }
 
-// ParsePosition will unmarshal the TicketPosition used to record the next position
-func ParsePosition(p sdk.Position) (TicketPosition, error) {
+// ParseSourcePosition will unmarshal the SourcePosition, positions recorded before
+// multiple entities were supported are treated as the position of tickets
+func ParseSourcePosition(p sdk.Position) (SourcePosition, error) {
+	if len(p) == 0 {
+		return SourcePosition{Entities: make(map[string]EntityPosition)}, nil
+	}
+
+	var sp SourcePosition
+	err := json.Unmarshal(p, &sp)
+	if err != nil {
+		return SourcePosition{}, fmt.Errorf("couldn't parse the source position: %w", err)
+	}
+	if sp.Entities != nil {
+		return sp, nil
+	}
+
+	tp, err := ParsePosition(p)
+	if err != nil {
+		return SourcePosition{}, err
+	}
+	sp.Entities = map[string]EntityPosition{legacyEntity: tp}
+	return sp, nil
+}
+
+// ParsePosition will unmarshal the EntityPosition used to record the next position
+func ParsePosition(p sdk.Position) (EntityPosition, error) {
 	var err error
 
 	if len(p) == 0 {
-		return TicketPosition{}, nil
+		return EntityPosition{}, nil
 	}
 
-	var tp TicketPosition
+	var tp EntityPosition
 	// parse the next position to sdk.Record
 	err = json.Unmarshal(p, &tp)
 	if err != nil {
-		return TicketPosition{}, fmt.Errorf("couldn't parse the after_cursor position: %w", err)
+		return EntityPosition{}, fmt.Errorf("couldn't parse the after_cursor position: %w", err)
 	}
 
 	return tp, err
@@ -24,7 +24,7 @@ import (
 )
 
 func TestToRecordPosition(t *testing.T) {
-	pos := TicketPosition{
+	pos := EntityPosition{
 		LastModified: time.Now(),
 		ID:           0,
 	}
@@ -38,7 +38,7 @@ func TestParsePosition(t *testing.T) {
 	tests := []struct {
 		name    string
 		pos     sdk.Position
-		want    TicketPosition
+		want    EntityPosition
 		isError bool
 	}{
 		{
@@ -46,7 +46,7 @@ func TestParsePosition(t *testing.T) {
 			pos:  []byte(`{"LastModified":"2022-05-08T02:48:21Z","ID":87}`),
 		},
 		{
-			want: TicketPosition{
+			want: EntityPosition{
 				ID: 87,
 			},
 			isError: false,
@@ -56,7 +56,7 @@ func TestParsePosition(t *testing.T) {
 			pos:  []byte{},
 		},
 		{
-			want:    TicketPosition{},
+			want:    EntityPosition{},
 			isError: false,
 		},
 	}
@@ -71,3 +71,50 @@ func TestParsePosition(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSourcePosition(t *testing.T) {
+	lastModified := time.Date(2022, 5, 8, 2, 48, 21, 0, time.UTC)
+	tests := []struct {
+		name    string
+		pos     sdk.Position
+		want    SourcePosition
+		isError bool
+	}{
+		{
+			name: "empty position",
+			pos:  nil,
+			want: SourcePosition{Entities: map[string]EntityPosition{}},
+		},
+		{
+			name: "position with multiple entities",
+			pos:  []byte(`{"entities":{"tickets":{"last_modified_time":"2022-05-08T02:48:21Z","id":87},"users":{"last_modified_time":"2022-05-08T02:48:21Z","id":12}}}`),
+			want: SourcePosition{Entities: map[string]EntityPosition{
+				"tickets": {LastModified: lastModified, ID: 87},
+				"users":   {LastModified: lastModified, ID: 12},
+			}},
+		},
+		{
+			name: "position recorded before multiple entities were supported",
+			pos:  []byte(`{"last_modified_time":"2022-05-08T02:48:21Z","id":87}`),
+			want: SourcePosition{Entities: map[string]EntityPosition{
+				"tickets": {LastModified: lastModified, ID: 87},
+			}},
+		},
+		{
+			name:    "invalid position",
+			pos:     []byte(`{"entities":`),
+			isError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ParseSourcePosition(tt.pos)
+			if tt.isError {
+				assert.NotNil(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projection
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Action is the way a redaction rule redacts the value of a field
+type Action string
+
+const (
+	// ActionDrop removes the field
+	ActionDrop Action = "drop"
+	// ActionHash replaces the value with the hex HMAC-SHA256 of the value, keyed with the salt,
+	// so equal values still match across records without revealing the value
+	ActionHash Action = "hash"
+	// ActionMask replaces the value with a fixed mask
+	ActionMask Action = "mask"
+)
+
+// Mask is the value of the masked fields
+const Mask = "****"
+
+// Rule redacts the field at the path
+type Rule struct {
+	Path   string
+	Action Action
+}
+
+// Projection selects and redacts the fields of the zendesk objects read by the source.
+// Paths are field names, nested fields are separated with dots, i.e. `via.source.from.address`.
+// The paths traverse arrays, applying to the field of every element.
+type Projection struct {
+	include [][]string // paths of the fields kept, every field if empty
+	exclude [][]string // paths of the fields removed
+	rules   []rule
+	salt    []byte
+}
+
+type rule struct {
+	path   []string
+	action Action
+}
+
+// New returns the projection keeping only the included fields, if any, removing the excluded ones,
+// then applying the redaction rules, in order. The salt is required by hash rules.
+func New(include, exclude []string, rules []Rule, salt string) (*Projection, error) {
+	p := &Projection{salt: []byte(salt)}
+	for _, path := range include {
+		p.include = append(p.include, splitPath(path))
+	}
+	for _, path := range exclude {
+		p.exclude = append(p.exclude, splitPath(path))
+	}
+	for _, r := range rules {
+		switch r.Action {
+		case ActionDrop, ActionMask:
+		case ActionHash:
+			if salt == "" {
+				return nil, fmt.Errorf("a salt is required to hash %q", r.Path)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported action %q for %q, supported actions: %s, %s, %s", r.Action, r.Path, ActionDrop, ActionHash, ActionMask)
+		}
+		p.rules = append(p.rules, rule{path: splitPath(r.Path), action: r.Action})
+	}
+	return p, nil
+}
+
+// ParseRules parses the comma separated list of `path:action` redaction rules,
+// i.e. `requester.email:hash,description:mask`
+func ParseRules(value string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		sep := strings.LastIndex(item, ":")
+		if sep <= 0 {
+			return nil, fmt.Errorf("rule %q should be formatted as path:action", item)
+		}
+		rules = append(rules, Rule{
+			Path:   strings.TrimSpace(item[:sep]),
+			Action: Action(strings.TrimSpace(item[sep+1:])),
+		})
+	}
+	return rules, nil
+}
+
+// Apply returns the projected copy of the object, the object itself is left unchanged
+func (p *Projection) Apply(object map[string]interface{}) (map[string]interface{}, error) {
+	var projected map[string]interface{}
+	if len(p.include) > 0 {
+		projected = make(map[string]interface{})
+		for _, path := range p.include {
+			include(object, projected, path)
+		}
+	} else {
+		projected = copyValue(object).(map[string]interface{})
+	}
+
+	for _, path := range p.exclude {
+		remove(projected, path)
+	}
+	for _, r := range p.rules {
+		if r.action == ActionDrop {
+			remove(projected, r.path)
+			continue
+		}
+		if err := p.replace(projected, r); err != nil {
+			return nil, err
+		}
+	}
+	return projected, nil
+}
+
+// include copies the value at the path from the source to the destination object, creating the parent objects
+func include(src, dst map[string]interface{}, path []string) {
+	value, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = copyValue(value)
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := dst[path[0]].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			dst[path[0]] = child
+		}
+		include(v, child, path[1:])
+	case []interface{}:
+		list, ok := dst[path[0]].([]interface{})
+		if !ok {
+			list = make([]interface{}, len(v))
+			dst[path[0]] = list
+		}
+		for i, elem := range v {
+			elemSrc, ok := elem.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			elemDst, ok := list[i].(map[string]interface{})
+			if !ok {
+				elemDst = make(map[string]interface{})
+				list[i] = elemDst
+			}
+			include(elemSrc, elemDst, path[1:])
+		}
+	}
+}
+
+// remove deletes the field at the path
+func remove(object map[string]interface{}, path []string) {
+	_ = walk(object, path, func(parent map[string]interface{}, field string) error {
+		delete(parent, field)
+		return nil
+	})
+}
+
+// replace redacts the value of the field at the path, null values are kept as they don't reveal anything
+func (p *Projection) replace(object map[string]interface{}, r rule) error {
+	return walk(object, r.path, func(parent map[string]interface{}, field string) error {
+		value := parent[field]
+		if value == nil {
+			return nil
+		}
+		if r.action == ActionMask {
+			parent[field] = Mask
+			return nil
+		}
+		hash, err := p.hash(value)
+		if err != nil {
+			return fmt.Errorf("could not hash %q: %w", strings.Join(r.path, "."), err)
+		}
+		parent[field] = hash
+		return nil
+	})
+}
+
+// hash returns the keyed hash of the value, strings are hashed as is, other values as JSON
+func (p *Projection) hash(value interface{}) (string, error) {
+	data, ok := value.(string)
+	if !ok {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		data = string(raw)
+	}
+	mac := hmac.New(sha256.New, p.salt)
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// walk calls fn with the parent object of every existing field at the path
+func walk(object map[string]interface{}, path []string, fn func(parent map[string]interface{}, field string) error) error {
+	if len(path) == 1 {
+		if _, ok := object[path[0]]; !ok {
+			return nil
+		}
+		return fn(object, path[0])
+	}
+
+	switch v := object[path[0]].(type) {
+	case map[string]interface{}:
+		return walk(v, path[1:], fn)
+	case []interface{}:
+		for _, elem := range v {
+			if child, ok := elem.(map[string]interface{}); ok {
+				if err := walk(child, path[1:], fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// copyValue deep copies the objects and arrays of a JSON value, so the copy can be changed
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			c[key] = copyValue(val)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, val := range v {
+			c[i] = copyValue(val)
+		}
+		return c
+	default:
+		return value
+	}
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimSpace(path), ".")
+}
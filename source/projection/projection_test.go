@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projection
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ticket = `{
+	"id": 35436,
+	"subject": "Help, my printer is on fire!",
+	"description": "Call me at +1 555 0100",
+	"requester": {"id": 20978392, "email": "jdoe@example.com", "phone": null},
+	"via": {"channel": "email", "source": {"from": {"address": "jdoe@example.com", "name": "John Doe"}}},
+	"custom_fields": [{"id": 27642, "value": "745"}, {"id": 27648, "value": "yes"}]
+}`
+
+func TestProjection_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		rules   []Rule
+		want    string
+	}{
+		{
+			name:    "include nested fields",
+			include: []string{"id", "via.source.from.name", "custom_fields.value", "missing.field"},
+			want:    `{"id":35436,"via":{"source":{"from":{"name":"John Doe"}}},"custom_fields":[{"value":"745"},{"value":"yes"}]}`,
+		},
+		{
+			name:    "exclude nested fields",
+			exclude: []string{"description", "requester", "via.source", "custom_fields.id"},
+			want:    `{"id":35436,"subject":"Help, my printer is on fire!","via":{"channel":"email"},"custom_fields":[{"value":"745"},{"value":"yes"}]}`,
+		},
+		{
+			name:    "redact included fields",
+			include: []string{"id", "description", "requester", "via.source.from.address"},
+			rules: []Rule{
+				{Path: "description", Action: ActionMask},
+				{Path: "requester.email", Action: ActionHash},
+				{Path: "requester.phone", Action: ActionMask},
+				{Path: "via.source.from.address", Action: ActionHash},
+				{Path: "requester.id", Action: ActionDrop},
+			},
+			want: `{
+				"id": 35436,
+				"description": "****",
+				"requester": {"email": "9e70b9e96aba85669a100613bbf233b549f988bb774a0b5d2f41c47375b1e955", "phone": null},
+				"via": {"source": {"from": {"address": "9e70b9e96aba85669a100613bbf233b549f988bb774a0b5d2f41c47375b1e955"}}}
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var object map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(ticket), &object))
+
+			p, err := New(tt.include, tt.exclude, tt.rules, "dummy_salt")
+			assert.NoError(t, err)
+			got, err := p.Apply(object)
+			assert.NoError(t, err)
+
+			payload, err := json.Marshal(got)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(payload))
+
+			// the object itself is left unchanged
+			original, err := json.Marshal(object)
+			assert.NoError(t, err)
+			assert.JSONEq(t, ticket, string(original))
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(" requester.email:hash, description:mask,,via.source.from.address:drop")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Path: "requester.email", Action: ActionHash},
+		{Path: "description", Action: ActionMask},
+		{Path: "via.source.from.address", Action: ActionDrop},
+	}, rules)
+
+	_, err = ParseRules("requester.email")
+	assert.EqualError(t, err, `rule "requester.email" should be formatted as path:action`)
+}
+
+func TestNew_Errors(t *testing.T) {
+	_, err := New(nil, nil, []Rule{{Path: "requester.email", Action: ActionHash}}, "")
+	assert.EqualError(t, err, `a salt is required to hash "requester.email"`)
+
+	_, err = New(nil, nil, []Rule{{Path: "requester.email", Action: "encrypt"}}, "dummy_salt")
+	assert.EqualError(t, err, `unsupported action "encrypt" for "requester.email", supported actions: drop, hash, mask`)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/source/iterator"
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/source/schema"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// schemaChangeKey is the key of the schema change records
+const schemaChangeKey = "schema"
+
+// schemaChanged reports whether the schema of the tickets changed since the last schema change record emitted
+func (s *Source) schemaChanged() bool {
+	return s.config.InferSchema && s.ticketFields.Schema().Fingerprint != s.schemaFingerprint
+}
+
+// schemaChange returns the schema change record of the current schema, positioned at the last record emitted,
+// so acknowledging it doesn't move the entities
+func (s *Source) schemaChange() (sdk.Record, error) {
+	current := s.ticketFields.Schema()
+	sourcePos, err := position.ParseSourcePosition(s.lastPosition)
+	if err != nil {
+		return sdk.Record{}, err
+	}
+	sourcePos.Schema = current.Fingerprint
+	pos, err := sourcePos.ToRecordPosition()
+	if err != nil {
+		return sdk.Record{}, err
+	}
+	payload, err := json.Marshal(schema.NewChange(s.schemaFingerprint, s.schema, current))
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("error marshaling the schema change: %w", err)
+	}
+
+	s.schema, s.schemaFingerprint, s.lastPosition = current, current.Fingerprint, pos
+	return sdk.Record{
+		Position: pos,
+		Metadata: map[string]string{
+			iterator.MetadataEntity:    zendesk.EntityTickets,
+			schema.MetadataChange:      "true",
+			schema.MetadataFingerprint: current.Fingerprint,
+		},
+		CreatedAt: time.Now().UTC(),
+		Key:       sdk.RawData(schemaChangeKey),
+		Payload:   sdk.RawData(payload),
+	}, nil
+}
+
+// tagSchema records the fingerprint of the last schema emitted in the position of the record,
+// and in the metadata of the ticket records
+func (s *Source) tagSchema(r sdk.Record) (sdk.Record, error) {
+	sourcePos, err := position.ParseSourcePosition(r.Position)
+	if err != nil {
+		return sdk.Record{}, err
+	}
+	sourcePos.Schema = s.schemaFingerprint
+	r.Position, err = sourcePos.ToRecordPosition()
+	if err != nil {
+		return sdk.Record{}, err
+	}
+
+	if isTicket(r) {
+		if r.Metadata == nil {
+			r.Metadata = make(map[string]string)
+		}
+		r.Metadata[schema.MetadataFingerprint] = s.schemaFingerprint
+	}
+	return r, nil
+}
+
+// isTicket reports whether the record is a ticket, read from the tickets entity or searched
+func isTicket(r sdk.Record) bool {
+	switch r.Metadata[iterator.MetadataEntity] {
+	case zendesk.EntityTickets:
+		return true
+	case zendesk.EntitySearch:
+		return r.Metadata["result_type"] == "ticket"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+)
+
+// record metadata keys of the schema
+const (
+	// MetadataFingerprint holds the fingerprint of the schema of the ticket records
+	MetadataFingerprint = "schema_fingerprint"
+	// MetadataChange is set to "true" for the schema change records, emitted when the schema of the tickets changes
+	MetadataChange = "schema_change"
+)
+
+// types of the fields
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeDate    = "date"   // YYYY-MM-DD date
+	TypeTime    = "time"   // RFC3339 time
+	TypeArray   = "array"  // array of values, i.e. the tags
+	TypeObject  = "object" // nested object, i.e. via
+)
+
+// Field is a field of the ticket payloads
+type Field struct {
+	Name   string  `json:"name"` // payload field, `custom_fields.<id>` for the custom fields, `custom_fields.<key>` once flattened
+	Type   string  `json:"type"`
+	Title  string  `json:"title,omitempty"`  // title of the custom field
+	ID     float64 `json:"id,omitempty"`     // id of the custom field
+	Custom bool    `json:"custom,omitempty"` // the field is a custom field, stored in the `custom_fields` of the tickets
+}
+
+// Schema is the schema of the ticket payloads, inferred from the ticket fields of the account
+type Schema struct {
+	Fingerprint string  `json:"fingerprint"` // hash of the fields, which changes when a field is added, removed or changed
+	Fields      []Field `json:"fields"`
+}
+
+// ticketFields are the fields of every ticket payload, custom fields aside
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/tickets/tickets/#json-format
+var ticketFields = []Field{
+	{Name: "id", Type: TypeInteger},
+	{Name: "url", Type: TypeString},
+	{Name: "external_id", Type: TypeString},
+	{Name: "created_at", Type: TypeTime},
+	{Name: "updated_at", Type: TypeTime},
+	{Name: "generated_timestamp", Type: TypeInteger},
+	{Name: "type", Type: TypeString},
+	{Name: "subject", Type: TypeString},
+	{Name: "raw_subject", Type: TypeString},
+	{Name: "description", Type: TypeString},
+	{Name: "priority", Type: TypeString},
+	{Name: "status", Type: TypeString},
+	{Name: "custom_status_id", Type: TypeInteger},
+	{Name: "recipient", Type: TypeString},
+	{Name: "requester_id", Type: TypeInteger},
+	{Name: "submitter_id", Type: TypeInteger},
+	{Name: "assignee_id", Type: TypeInteger},
+	{Name: "organization_id", Type: TypeInteger},
+	{Name: "group_id", Type: TypeInteger},
+	{Name: "collaborator_ids", Type: TypeArray},
+	{Name: "follower_ids", Type: TypeArray},
+	{Name: "email_cc_ids", Type: TypeArray},
+	{Name: "forum_topic_id", Type: TypeInteger},
+	{Name: "problem_id", Type: TypeInteger},
+	{Name: "has_incidents", Type: TypeBoolean},
+	{Name: "is_public", Type: TypeBoolean},
+	{Name: "due_at", Type: TypeTime},
+	{Name: "tags", Type: TypeArray},
+	{Name: "via", Type: TypeObject},
+	{Name: "satisfaction_rating", Type: TypeObject},
+	{Name: "sharing_agreement_ids", Type: TypeArray},
+	{Name: "followup_ids", Type: TypeArray},
+	{Name: "ticket_form_id", Type: TypeInteger},
+	{Name: "brand_id", Type: TypeInteger},
+	{Name: "allow_channelback", Type: TypeBoolean},
+	{Name: "allow_attachments", Type: TypeBoolean},
+	{Name: "from_messaging_channel", Type: TypeBoolean},
+	{Name: "custom_fields", Type: TypeArray},
+}
+
+// Infer returns the schema of the ticket payloads, with the active custom fields of the ticket fields, ordered by id.
+// The keys of the custom fields are set when they are flattened, nil otherwise.
+func Infer(fields []zendesk.TicketField, keys map[float64]string) (*Schema, error) {
+	s := &Schema{Fields: append([]Field(nil), ticketFields...)}
+	for i := range s.Fields {
+		if keys != nil && s.Fields[i].Name == "custom_fields" {
+			// the values keyed by field, once flattened
+			s.Fields[i].Type = TypeObject
+		}
+	}
+
+	var custom []Field
+	for _, field := range fields {
+		if !field.Custom() || !field.Active {
+			continue
+		}
+		name, fieldType := strconv.FormatFloat(field.ID, 'f', -1, 64), FieldType(field.Type)
+		if keys != nil {
+			name, fieldType = keys[field.ID], flattenedFieldType(field.Type)
+		}
+		custom = append(custom, Field{
+			Name:   "custom_fields." + name,
+			Type:   fieldType,
+			Title:  field.Title,
+			ID:     field.ID,
+			Custom: true,
+		})
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].ID < custom[j].ID })
+	s.Fields = append(s.Fields, custom...)
+
+	data, err := json.Marshal(s.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the schema fields: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	s.Fingerprint = hex.EncodeToString(sum[:16])
+	return s, nil
+}
+
+// FieldType returns the type of the values of the custom field type
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/tickets/ticket_fields/#json-format
+func FieldType(fieldType string) string {
+	switch fieldType {
+	case "integer", "lookup":
+		return TypeInteger
+	case "decimal":
+		return TypeNumber
+	case "checkbox":
+		return TypeBoolean
+	case "date":
+		return TypeDate
+	case "multiselect":
+		return TypeArray
+	default:
+		// text, textarea, regexp, partialcreditcard and tagger, whose value is the tag of the selected option
+		return TypeString
+	}
+}
+
+// flattenedFieldType returns the type of the values of the custom field type, once flattened
+func flattenedFieldType(fieldType string) string {
+	switch fieldType {
+	case "date":
+		return TypeTime
+	case "tagger":
+		// the selected option, with its tag and name
+		return TypeObject
+	default:
+		return FieldType(fieldType)
+	}
+}
+
+// Change is the payload of the schema change records
+type Change struct {
+	Fingerprint         string  `json:"fingerprint"`
+	PreviousFingerprint string  `json:"previous_fingerprint,omitempty"` // empty for the first schema emitted
+	Fields              []Field `json:"fields"`
+	// the fields added, removed and changed since the previous schema, only set when the previous schema is known,
+	// which isn't the case for the first change after a restart
+	Added   []Field `json:"added,omitempty"`
+	Removed []Field `json:"removed,omitempty"`
+	Changed []Field `json:"changed,omitempty"`
+}
+
+// NewChange returns the change from the previous schema to the schema, the previous schema is nil when only
+// its fingerprint is known
+func NewChange(previousFingerprint string, previous, s *Schema) Change {
+	change := Change{Fingerprint: s.Fingerprint, PreviousFingerprint: previousFingerprint, Fields: s.Fields}
+	if previous == nil {
+		return change
+	}
+
+	before := make(map[string]Field, len(previous.Fields))
+	for _, field := range previous.Fields {
+		before[field.Name] = field
+	}
+	for _, field := range s.Fields {
+		old, ok := before[field.Name]
+		switch {
+		case !ok:
+			change.Added = append(change.Added, field)
+		case old != field:
+			change.Changed = append(change.Changed, field)
+		}
+		delete(before, field.Name)
+	}
+	for _, field := range previous.Fields {
+		if _, ok := before[field.Name]; ok {
+			change.Removed = append(change.Removed, field)
+		}
+	}
+	return change
+}
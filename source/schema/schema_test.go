@@ -0,0 +1,151 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFields = []zendesk.TicketField{
+	{ID: 1, Type: "subject", Title: "Subject", Active: true},
+	{ID: 360002, Type: "decimal", Title: "Refund", Active: true, Removable: true},
+	{ID: 360001, Type: "tagger", Title: "Plan", Active: true, Removable: true},
+	{ID: 360003, Type: "date", Title: "Renewal", Active: false, Removable: true},
+}
+
+func TestInfer(t *testing.T) {
+	s, err := Infer(testFields, nil)
+	assert.NoError(t, err)
+
+	// the active custom fields follow the ticket fields, ordered by id
+	custom := s.Fields[len(ticketFields):]
+	assert.Equal(t, []Field{
+		{Name: "custom_fields.360001", Type: TypeString, Title: "Plan", ID: 360001, Custom: true},
+		{Name: "custom_fields.360002", Type: TypeNumber, Title: "Refund", ID: 360002, Custom: true},
+	}, custom)
+	assert.Len(t, s.Fingerprint, 32)
+
+	// the fingerprint doesn't depend on the order of the ticket fields
+	reversed := []zendesk.TicketField{testFields[3], testFields[2], testFields[1], testFields[0]}
+	same, err := Infer(reversed, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, s.Fingerprint, same.Fingerprint)
+
+	// activating a field changes the fingerprint
+	activated := append([]zendesk.TicketField(nil), testFields...)
+	activated[3].Active = true
+	other, err := Infer(activated, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, s.Fingerprint, other.Fingerprint)
+}
+
+func TestInfer_Flattened(t *testing.T) {
+	s, err := Infer(testFields, map[float64]string{360001: "Plan", 360002: "refund", 360003: "Renewal"})
+	assert.NoError(t, err)
+	assert.Contains(t, s.Fields, Field{Name: "custom_fields", Type: TypeObject})
+	// the flattened tagger fields are the selected option
+	assert.Equal(t, []Field{
+		{Name: "custom_fields.Plan", Type: TypeObject, Title: "Plan", ID: 360001, Custom: true},
+		{Name: "custom_fields.refund", Type: TypeNumber, Title: "Refund", ID: 360002, Custom: true},
+	}, s.Fields[len(ticketFields):])
+
+	raw, err := Infer(testFields, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, raw.Fingerprint, s.Fingerprint)
+}
+
+func TestFieldType(t *testing.T) {
+	tests := []struct {
+		fieldType string
+		want      string
+		flattened string
+	}{
+		{fieldType: "integer", want: TypeInteger, flattened: TypeInteger},
+		{fieldType: "lookup", want: TypeInteger, flattened: TypeInteger},
+		{fieldType: "decimal", want: TypeNumber, flattened: TypeNumber},
+		{fieldType: "checkbox", want: TypeBoolean, flattened: TypeBoolean},
+		{fieldType: "date", want: TypeDate, flattened: TypeTime},
+		{fieldType: "tagger", want: TypeString, flattened: TypeObject},
+		{fieldType: "multiselect", want: TypeArray, flattened: TypeArray},
+		{fieldType: "text", want: TypeString, flattened: TypeString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fieldType, func(t *testing.T) {
+			assert.Equal(t, tt.want, FieldType(tt.fieldType))
+			assert.Equal(t, tt.flattened, flattenedFieldType(tt.fieldType))
+		})
+	}
+}
+
+func TestNewChange(t *testing.T) {
+	previous, err := Infer(testFields, nil)
+	assert.NoError(t, err)
+
+	fields := append([]zendesk.TicketField(nil), testFields...)
+	fields[1].Title = "Refund amount" // changed
+	fields[2].Active = false          // removed
+	// added
+	fields = append(fields, zendesk.TicketField{ID: 360004, Type: "checkbox", Active: true, Removable: true})
+	s, err := Infer(fields, nil)
+	assert.NoError(t, err)
+
+	change := NewChange(previous.Fingerprint, previous, s)
+	assert.Equal(t, s.Fingerprint, change.Fingerprint)
+	assert.Equal(t, previous.Fingerprint, change.PreviousFingerprint)
+	assert.Equal(t, []Field{{Name: "custom_fields.360004", Type: TypeBoolean, ID: 360004, Custom: true}}, change.Added)
+	assert.Equal(t, []Field{{Name: "custom_fields.360001", Type: TypeString, Title: "Plan", ID: 360001, Custom: true}}, change.Removed)
+	assert.Equal(t, []Field{{Name: "custom_fields.360002", Type: TypeNumber, Title: "Refund amount", ID: 360002, Custom: true}}, change.Changed)
+
+	// only the fingerprint of the previous schema is known after a restart
+	change = NewChange(previous.Fingerprint, nil, s)
+	assert.Nil(t, change.Added)
+	assert.Equal(t, s.Fields, change.Fields)
+}
+
+func TestWatcher(t *testing.T) {
+	fail := false
+	fields := testFields
+	w := newWatcher(func(ctx context.Context) ([]zendesk.TicketField, error) {
+		if fail {
+			return nil, errors.New("forbidden")
+		}
+		return fields, nil
+	}, 10*time.Millisecond, nil)
+
+	assert.NoError(t, w.Start(context.Background()))
+	first := w.Schema()
+	assert.Equal(t, testFields, w.Fields())
+	w.Stop()
+
+	// the refresh failures keep the last schema
+	w = newWatcher(w.fetch, time.Hour, nil)
+	assert.NoError(t, w.refresh(context.Background()))
+	fail = true
+	assert.Error(t, w.refresh(context.Background()))
+	assert.Equal(t, first, w.Schema())
+
+	// the first fetch must succeed
+	w = newWatcher(w.fetch, time.Hour, nil)
+	assert.Error(t, w.Start(context.Background()))
+	w.Stop()
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+)
+
+// Watcher keeps the ticket fields of the account and their schema, refreshed periodically in the background
+type Watcher struct {
+	fetch  func(ctx context.Context) ([]zendesk.TicketField, error)
+	keys   func(fields []zendesk.TicketField) map[float64]string // keys of the flattened custom fields, nil if not flattened
+	period time.Duration
+
+	mux    sync.Mutex
+	fields []zendesk.TicketField
+	schema *Schema
+
+	stop chan struct{}
+	done chan struct{} // closed once the refreshes stopped, nil till started
+}
+
+// NewWatcher returns the watcher of the ticket fields of the account, refreshed every period.
+// The keys return the keys of the custom fields when they are flattened, they are nil otherwise.
+func NewWatcher(client *zendesk.Client, period time.Duration, keys func(fields []zendesk.TicketField) map[float64]string) *Watcher {
+	return newWatcher(func(ctx context.Context) ([]zendesk.TicketField, error) {
+		return zendesk.FetchTicketFields(ctx, client)
+	}, period, keys)
+}
+
+func newWatcher(
+	fetch func(ctx context.Context) ([]zendesk.TicketField, error),
+	period time.Duration,
+	keys func(fields []zendesk.TicketField) map[float64]string,
+) *Watcher {
+	return &Watcher{
+		fetch:  fetch,
+		keys:   keys,
+		period: period,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start fetches the ticket fields, failing if they can't be fetched, and refreshes them in the background till stopped
+func (w *Watcher) Start(ctx context.Context) error {
+	if err := w.refresh(ctx); err != nil {
+		return err
+	}
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				// the last ticket fields are kept till they are fetched again
+				if err := w.refresh(ctx); err != nil {
+					sdk.Logger(ctx).Warn().Err(err).Msg("could not refresh the ticket fields, retrying in the next refresh")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops refreshing the ticket fields, once started
+func (w *Watcher) Stop() {
+	close(w.stop)
+	if w.done != nil {
+		<-w.done
+	}
+}
+
+// Schema returns the schema of the last ticket fields fetched
+func (w *Watcher) Schema() *Schema {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.schema
+}
+
+// Fields returns the last ticket fields fetched
+func (w *Watcher) Fields() []zendesk.TicketField {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.fields
+}
+
+func (w *Watcher) refresh(ctx context.Context) error {
+	fields, err := w.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	var keys map[float64]string
+	if w.keys != nil {
+		keys = w.keys(fields)
+	}
+	s, err := Infer(fields, keys)
+	if err != nil {
+		return err
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.schema != nil && w.schema.Fingerprint != s.Fingerprint {
+		sdk.Logger(ctx).Info().
+			Str("previous_fingerprint", w.schema.Fingerprint).
+			Str("fingerprint", s.Fingerprint).
+			Msg("ticket schema changed")
+	}
+	w.fields = fields
+	w.schema = s
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/source/iterator"
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/source/schema"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/stretchr/testify/assert"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+func TestSource_SchemaChange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ticket_fields":[{"id":360001,"type":"tagger","title":"Plan","active":true,"removable":true}]}`))
+	}))
+	defer server.Close()
+	watcher := schema.NewWatcher(zendesk.NewClient(server.URL), time.Hour, nil)
+	assert.NoError(t, watcher.Start(context.Background()))
+	defer watcher.Stop()
+
+	lastPos := position.SourcePosition{Entities: map[string]position.EntityPosition{
+		zendesk.EntityTickets: {LastModified: time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC), ID: 12},
+	}}
+	rp, err := lastPos.ToRecordPosition()
+	assert.NoError(t, err)
+	s := &Source{config: Config{InferSchema: true}, ticketFields: watcher, schemaFingerprint: "0123456789abcdef", lastPosition: rp}
+
+	// the schema change record is positioned at the last record emitted, with the fingerprint of the schema
+	assert.True(t, s.schemaChanged())
+	record, err := s.schemaChange()
+	assert.NoError(t, err)
+	fingerprint := watcher.Schema().Fingerprint
+	assert.Equal(t, "true", record.Metadata[schema.MetadataChange])
+	assert.Equal(t, fingerprint, record.Metadata[schema.MetadataFingerprint])
+	changePos, err := position.ParseSourcePosition(record.Position)
+	assert.NoError(t, err)
+	assert.Equal(t, lastPos.Entities, changePos.Entities)
+	assert.Equal(t, fingerprint, changePos.Schema)
+
+	var change schema.Change
+	assert.NoError(t, json.Unmarshal(record.Payload.Bytes(), &change))
+	assert.Equal(t, "0123456789abcdef", change.PreviousFingerprint)
+	assert.Equal(t, watcher.Schema().Fields, change.Fields)
+	assert.False(t, s.schemaChanged())
+
+	// the ticket records are tagged with the fingerprint
+	record, err = s.tagSchema(sdk.Record{
+		Position: rp,
+		Metadata: map[string]string{iterator.MetadataEntity: zendesk.EntityTickets},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprint, record.Metadata[schema.MetadataFingerprint])
+	recordPos, err := position.ParseSourcePosition(record.Position)
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprint, recordPos.Schema)
+
+	record, err = s.tagSchema(sdk.Record{
+		Position: rp,
+		Metadata: map[string]string{iterator.MetadataEntity: zendesk.EntityUsers},
+	})
+	assert.NoError(t, err)
+	assert.NotContains(t, record.Metadata, schema.MetadataFingerprint)
+}
@@ -19,9 +19,14 @@ package source
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/conduitio/conduit-connector-zendesk/source/changes"
 	"github.com/conduitio/conduit-connector-zendesk/source/iterator"
 	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/conduitio/conduit-connector-zendesk/source/schema"
+	"github.com/conduitio/conduit-connector-zendesk/source/webhook"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 )
@@ -30,11 +35,20 @@ type Source struct {
 	sdk.UnimplementedSource
 	config   Config
 	iterator Iterator
+	receiver *webhook.Receiver // receiver of the webhook requests, in webhook mode
+	changes  *changes.Detector // detector of the changes of the tracked fields, nil to emit every record
+	done     bool              // every record till the end time was read
+
+	ticketFields      *schema.Watcher // watcher of the ticket fields, nil if they aren't fetched
+	schema            *schema.Schema  // last schema emitted, nil till emitted after a restart, as only its fingerprint is known
+	schemaFingerprint string          // fingerprint of the last schema emitted
+	lastPosition      sdk.Position    // position of the last record emitted, at which the schema change records are positioned
 }
 
 type Iterator interface {
 	HasNext(ctx context.Context) bool
 	Next(ctx context.Context) (sdk.Record, error)
+	Done() bool
 	Stop()
 }
 
@@ -54,18 +68,97 @@ func (s *Source) Configure(ctx context.Context, cfg map[string]string) error {
 
 // Open prepare the plugin to start sending records from the given position
 func (s *Source) Open(ctx context.Context, rp sdk.Position) error {
-	ticketPos, err := position.ParsePosition(rp)
+	sourcePos, err := position.ParseSourcePosition(rp)
 	if err != nil {
 		return err
 	}
 
+	entities := make([]zendesk.Entity, 0, len(s.config.Entities))
+	for _, name := range s.config.Entities {
+		if name == zendesk.EntitySearch {
+			entities = append(entities, zendesk.SearchEntity(s.config.SearchQuery, s.config.SearchType))
+			continue
+		}
+		entity, ok := zendesk.LookupEntity(name)
+		if !ok {
+			return fmt.Errorf("unsupported entity %q", name)
+		}
+		entities = append(entities, entity)
+	}
+
+	client, err := zendesk.NewAccountClient(s.config.Config)
+	if err != nil {
+		return err
+	}
+
+	if len(s.config.ChangeFields) > 0 {
+		s.changes, err = changes.New(s.config.ChangeFields, s.config.ChangeStorePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var customFields *zendesk.CustomFields
+	if s.config.InferSchema || s.config.FlattenFields {
+		var keys func(fields []zendesk.TicketField) map[float64]string
+		if s.config.FlattenFields {
+			keys = func(fields []zendesk.TicketField) map[float64]string {
+				return zendesk.CustomFieldKeys(fields, s.config.FieldAliases)
+			}
+		}
+		s.ticketFields = schema.NewWatcher(client, s.config.TicketFieldsRefreshPeriod, keys)
+		if err := s.ticketFields.Start(ctx); err != nil {
+			return err
+		}
+		if s.config.FlattenFields {
+			customFields = zendesk.NewCustomFields(s.ticketFields.Fields, s.config.FieldAliases)
+		}
+		s.schemaFingerprint = sourcePos.Schema
+		s.lastPosition = rp
+	}
+
+	accounts := make([]iterator.Account, 0, len(s.config.Accounts))
+	for _, account := range s.config.Accounts {
+		accountClient, err := zendesk.NewAccountClient(account)
+		if err != nil {
+			return fmt.Errorf("account %q: %w", account.Domain, err)
+		}
+		accounts = append(accounts, iterator.Account{Subdomain: account.Domain, Client: accountClient})
+	}
+
+	// the relative start and backfill end are anchored to the time the entities without position are first opened,
+	// and persisted in the positions of every record from then on
+	opened := time.Now().UTC()
+	opts := iterator.Options{
+		Filter:       s.config.Filter,
+		Projection:   s.config.Projection,
+		Backfill:     s.config.backfillAt(opened),
+		StartTime:    s.config.startAt(opened),
+		EndTime:      s.config.EndTime,
+		DedupWindow:  s.config.DedupWindow,
+		CustomFields: customFields,
+		Changes:      s.changes,
+		Accounts:     accounts,
+	}
+	pollingPeriod := s.config.PollingPeriod
+	if s.config.Webhook != nil {
+		s.receiver = webhook.NewReceiver(*s.config.Webhook, entities)
+		if err := s.receiver.Start(ctx); err != nil {
+			return err
+		}
+		opts.Events = s.receiver.Events()
+		// the exports only reconcile the objects missed by the webhook
+		pollingPeriod = s.config.Webhook.SweepPeriod
+	}
+
 	s.iterator, err = iterator.NewCDCIterator(
 		ctx,
-		s.config.UserName,
-		s.config.APIToken,
-		s.config.Domain,
-		s.config.PollingPeriod,
-		ticketPos,
+		client,
+		pollingPeriod,
+		entities,
+		s.config.Snapshot,
+		opts,
+		sourcePos,
 	)
 	if err != nil {
 		return err
@@ -75,7 +168,16 @@ func (s *Source) Open(ctx context.Context, rp sdk.Position) error {
 
 // Read gets the next object from the zendesk api
 func (s *Source) Read(ctx context.Context) (sdk.Record, error) {
+	if s.schemaChanged() {
+		return s.schemaChange()
+	}
 	if !s.iterator.HasNext(ctx) {
+		if !s.done && s.iterator.Done() {
+			s.done = true
+			sdk.Logger(ctx).Info().
+				Time("end_time", s.config.EndTime).
+				Msg("every record till the end time was read, no more records will be read")
+		}
 		return sdk.Record{}, sdk.ErrBackoffRetry
 	}
 
@@ -83,11 +185,34 @@ func (s *Source) Read(ctx context.Context) (sdk.Record, error) {
 	if err != nil {
 		return sdk.Record{}, err
 	}
+	if s.config.InferSchema {
+		r, err = s.tagSchema(r)
+		if err != nil {
+			return sdk.Record{}, err
+		}
+	}
+	s.lastPosition = r.Position
 	return r, nil
 }
 
 func (s *Source) Teardown(ctx context.Context) error {
 	sdk.Logger(ctx).Trace().Msg("shutting down zendesk client")
+	if s.receiver != nil {
+		if err := s.receiver.Stop(ctx); err != nil {
+			sdk.Logger(ctx).Warn().Err(err).Msg("could not stop the webhook receiver")
+		}
+		s.receiver = nil
+	}
+	if s.ticketFields != nil {
+		s.ticketFields.Stop()
+		s.ticketFields = nil
+	}
+	if s.changes != nil {
+		if err := s.changes.Close(); err != nil {
+			sdk.Logger(ctx).Warn().Err(err).Msg("could not close the change store")
+		}
+		s.changes = nil
+	}
 	if s.iterator != nil {
 		s.iterator.Stop()
 		s.iterator = nil
@@ -96,13 +221,19 @@ func (s *Source) Teardown(ctx context.Context) error {
 }
 
 func (s *Source) Ack(ctx context.Context, pos sdk.Position) error {
-	ticketPos, err := position.ParsePosition(pos)
+	sourcePos, err := position.ParseSourcePosition(pos)
 	if err != nil {
 		return fmt.Errorf("invalid position: %w", err)
 	}
-	sdk.Logger(ctx).Trace().
-		Float64("id", ticketPos.ID).
-		Time("update_time", ticketPos.LastModified).
-		Msg("ack received")
+	for entity, entityPos := range sourcePos.Entities {
+		sdk.Logger(ctx).Trace().
+			Str("entity", entity).
+			Float64("id", entityPos.ID).
+			Time("update_time", entityPos.LastModified).
+			Msg("ack received")
+	}
+	if s.changes != nil {
+		return s.changes.Ack(pos)
+	}
 	return nil
 }
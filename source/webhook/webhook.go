@@ -0,0 +1,238 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+const (
+	// HeaderSignature is the header holding the base64 HMAC-SHA256 signature of the webhook request
+	HeaderSignature = "X-Zendesk-Webhook-Signature"
+	// HeaderSignatureTimestamp is the header holding the timestamp signed along with the request body
+	HeaderSignatureTimestamp = "X-Zendesk-Webhook-Signature-Timestamp"
+
+	// maxBodySize is the maximum size of the request bodies read
+	maxBodySize = 10 << 20
+)
+
+// Config is the config of the webhook receiver
+type Config struct {
+	Address     string        // address the receiver listens on, i.e. `:8080`
+	Path        string        // path prefix of the entity routes
+	Secret      string        // signing secret of the webhook, it may reference a secret
+	ObjectField string        // dotted path of the object in the request body, the body is the object if empty
+	Tolerance   time.Duration // maximum age of the signature timestamp, to reject replayed requests
+	SweepPeriod time.Duration // period of the incremental exports reconciling the missed events
+}
+
+// Event is an entity object received by the webhook
+type Event struct {
+	Entity string
+	Object map[string]interface{}
+}
+
+// Receiver is the http server receiving the zendesk webhook requests. The objects of an entity are posted to
+// the route named after the entity, under the path prefix, i.e. `/tickets`, and only accepted once read from the events.
+type Receiver struct {
+	cfg      Config
+	entities map[string]zendesk.Entity // entities received, keyed by route
+	events   chan Event
+	server   *http.Server
+	listener net.Listener
+	stopped  chan struct{} // closed once stopped, to release the requests waiting for the source
+	now      func() time.Time
+}
+
+// NewReceiver returns the receiver of the objects of the entities
+func NewReceiver(cfg Config, entities []zendesk.Entity) *Receiver {
+	r := &Receiver{
+		cfg:      cfg,
+		entities: make(map[string]zendesk.Entity, len(entities)),
+		events:   make(chan Event),
+		stopped:  make(chan struct{}),
+		now:      time.Now,
+	}
+	prefix := "/" + strings.Trim(cfg.Path, "/")
+	for _, entity := range entities {
+		r.entities[strings.TrimSuffix(prefix, "/")+"/"+entity.Name] = entity
+	}
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	return r
+}
+
+// Start listens on the address and serves the webhook requests in the background
+func (r *Receiver) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", r.cfg.Address)
+	if err != nil {
+		return fmt.Errorf("could not listen on %q: %w", r.cfg.Address, err)
+	}
+	r.listener = listener
+	go func() {
+		err := r.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			sdk.Logger(ctx).Error().Err(err).Msg("webhook receiver stopped")
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the receiver listens on, once started
+func (r *Receiver) Addr() string {
+	return r.listener.Addr().String()
+}
+
+// Events returns the channel of the received objects
+func (r *Receiver) Events() <-chan Event {
+	return r.events
+}
+
+// Stop stops the server, waiting for the requests in flight till the context is done
+func (r *Receiver) Stop(ctx context.Context) error {
+	close(r.stopped)
+	return r.server.Shutdown(ctx)
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	entity, ok := r.entities[req.URL.Path]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "could not read the body", http.StatusBadRequest)
+		return
+	}
+	if err := r.verify(req.Header, body); err != nil {
+		sdk.Logger(req.Context()).Warn().Err(err).Str("entity", entity.Name).Msg("webhook request rejected")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	object, err := r.parseObject(entity, body)
+	if err != nil {
+		sdk.Logger(req.Context()).Warn().Err(err).Str("entity", entity.Name).Msg("invalid webhook request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the request only succeeds once the object is read, so zendesk retries the objects which were not
+	select {
+	case r.events <- Event{Entity: entity.Name, Object: object}:
+		w.WriteHeader(http.StatusOK)
+	case <-req.Context().Done():
+		http.Error(w, "source not reading", http.StatusServiceUnavailable)
+	case <-r.stopped:
+		http.Error(w, "source stopped", http.StatusServiceUnavailable)
+	}
+}
+
+// verify checks the signature of the timestamp and body, and the age of the timestamp
+func (r *Receiver) verify(header http.Header, body []byte) error {
+	signature, timestamp := header.Get(HeaderSignature), header.Get(HeaderSignatureTimestamp)
+	if signature == "" || timestamp == "" {
+		return errors.New("missing signature")
+	}
+	secret, err := config.ResolveSecret(r.cfg.Secret)
+	if err != nil {
+		return fmt.Errorf("could not get the signing secret: %w", err)
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return errors.New("invalid signature")
+	}
+
+	if r.cfg.Tolerance > 0 {
+		signedAt, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid signature timestamp %q", timestamp)
+		}
+		if age := r.now().Sub(signedAt); age > r.cfg.Tolerance || age < -r.cfg.Tolerance {
+			return fmt.Errorf("signature timestamp %q out of tolerance", timestamp)
+		}
+	}
+	return nil
+}
+
+// Sign returns the signature of the timestamp and body, the base64 HMAC-SHA256 of the timestamp followed by the body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseObject extracts the entity object from the body, the ids of the objects sent as strings,
+// i.e. by trigger placeholders, are converted to numbers as returned by the exports
+func (r *Receiver) parseObject(entity zendesk.Entity, body []byte) (map[string]interface{}, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, fmt.Errorf("body should be a JSON object: %w", err)
+	}
+	if r.cfg.ObjectField != "" {
+		for _, field := range strings.Split(r.cfg.ObjectField, ".") {
+			child, ok := object[field].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("no object at %q in the body", r.cfg.ObjectField)
+			}
+			object = child
+		}
+	}
+
+	switch id := object[entity.IDField].(type) {
+	case float64:
+	case string:
+		n, err := strconv.ParseFloat(id, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", entity.IDField, id)
+		}
+		object[entity.IDField] = n
+	default:
+		return nil, fmt.Errorf("invalid type of %s encountered: %T", entity.IDField, id)
+	}
+	for _, field := range []string{entity.TimestampField, "created_at"} {
+		value, ok := object[field].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type of %s encountered: %T", field, object[field])
+		}
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("%s should be a RFC3339 time: %w", field, err)
+		}
+	}
+	return object, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "dGhpc19zZWNyZXRfaXNfZm9yX3Rlc3Rpbmdfb25seQ=="
+
+func TestReceiver_ServeHTTP(t *testing.T) {
+	now := time.Date(2022, 5, 8, 5, 50, 0, 0, time.UTC)
+	receiver := NewReceiver(Config{Path: "/zendesk", Secret: testSecret, ObjectField: "ticket", Tolerance: 5 * time.Minute},
+		[]zendesk.Entity{zendesk.Tickets})
+	receiver.now = func() time.Time { return now }
+	ticket := `{"ticket":{"id":"12","updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}}`
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		timestamp  string
+		secret     string
+		wantStatus int
+	}{{
+		name:       "valid request",
+		path:       "/zendesk/tickets",
+		body:       ticket,
+		timestamp:  "2022-05-08T05:49:58Z",
+		secret:     testSecret,
+		wantStatus: http.StatusOK,
+	}, {
+		name:       "unknown entity",
+		path:       "/zendesk/users",
+		body:       ticket,
+		timestamp:  "2022-05-08T05:49:58Z",
+		secret:     testSecret,
+		wantStatus: http.StatusNotFound,
+	}, {
+		name:       "invalid signature",
+		path:       "/zendesk/tickets",
+		body:       ticket,
+		timestamp:  "2022-05-08T05:49:58Z",
+		secret:     "other secret",
+		wantStatus: http.StatusUnauthorized,
+	}, {
+		name:       "replayed request",
+		path:       "/zendesk/tickets",
+		body:       ticket,
+		timestamp:  "2022-05-08T05:40:00Z",
+		secret:     testSecret,
+		wantStatus: http.StatusUnauthorized,
+	}, {
+		name:       "missing object",
+		path:       "/zendesk/tickets",
+		body:       `{"id":12}`,
+		timestamp:  "2022-05-08T05:49:58Z",
+		secret:     testSecret,
+		wantStatus: http.StatusBadRequest,
+	}, {
+		name:       "invalid update time",
+		path:       "/zendesk/tickets",
+		body:       `{"ticket":{"id":12,"updated_at":"May 8, 2022","created_at":"2022-05-08T05:49:55Z"}}`,
+		timestamp:  "2022-05-08T05:49:58Z",
+		secret:     testSecret,
+		wantStatus: http.StatusBadRequest,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set(HeaderSignature, Sign(tt.secret, tt.timestamp, []byte(tt.body)))
+			req.Header.Set(HeaderSignatureTimestamp, tt.timestamp)
+			events := make(chan Event, 1)
+			go func() {
+				select {
+				case event := <-receiver.Events():
+					events <- event
+				case <-time.After(time.Second):
+				}
+			}()
+
+			w := httptest.NewRecorder()
+			receiver.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			event := <-events
+			assert.Equal(t, zendesk.EntityTickets, event.Entity)
+			// the id is converted to a number
+			assert.Equal(t, float64(12), event.Object["id"])
+		})
+	}
+}
+
+func TestReceiver_Stop(t *testing.T) {
+	receiver := NewReceiver(Config{Address: "127.0.0.1:0", Secret: testSecret}, []zendesk.Entity{zendesk.Tickets})
+	assert.NoError(t, receiver.Start(context.Background()))
+
+	body := []byte(`{"id":12,"updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}`)
+	req, err := http.NewRequest(http.MethodPost, "http://"+receiver.Addr()+"/tickets", bytes.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set(HeaderSignature, Sign(testSecret, "1651988998", body))
+	req.Header.Set(HeaderSignatureTimestamp, "1651988998")
+
+	// the request waits for the source, till the receiver is stopped
+	done := make(chan int)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			close(done)
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, receiver.Stop(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, <-done)
+}
@@ -33,40 +33,320 @@ func Specification() sdk.Specification {
 		SourceParams: map[string]sdk.Parameter{
 			config.KeyDomain: {
 				Default:     "",
-				Required:    true,
-				Description: "A domain is referred as the organization name to which zendesk is registered",
+				Required:    false,
+				Description: "A domain is referred as the organization name to which zendesk is registered, required unless the base url is set",
+			},
+			config.KeyBaseURL: {
+				Default:     "",
+				Required:    false,
+				Description: "zendesk api url, overrides the url derived from the domain, i.e. for sandboxes, proxies or local servers",
+			},
+			config.KeyAuthType: {
+				Default:     "basic",
+				Required:    false,
+				Description: "authentication type, either basic (username and api token) or oauth",
 			},
 			config.KeyUserName: {
 				Default:     "",
-				Required:    true,
-				Description: "Login to zendesk performed using username",
+				Required:    false,
+				Description: "Login to zendesk performed using username, required for basic authentication",
 			},
 			config.KeyAPIToken: {
 				Default:     "",
-				Required:    true,
-				Description: "password to login",
+				Required:    false,
+				Description: "password to login, required for basic authentication, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			config.KeyOAuthAccessToken: {
+				Default:     "",
+				Required:    false,
+				Description: "OAuth access token, used as is for oauth authentication, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			config.KeyOAuthClientID: {
+				Default:     "",
+				Required:    false,
+				Description: "OAuth client id, used to request access tokens when no access token is set",
+			},
+			config.KeyOAuthClientSecret: {
+				Default:     "",
+				Required:    false,
+				Description: "OAuth client secret, required along with the client id, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			config.KeyOAuthRefreshToken: {
+				Default:     "",
+				Required:    false,
+				Description: "`${file:<path>}` reference of a writable file holding the OAuth refresh token, used to renew the access tokens instead of the client credentials grant, the rotated refresh token is written back to it",
+			},
+			config.KeyOAuthScope: {
+				Default:     "read write",
+				Required:    false,
+				Description: "OAuth scope of the requested access tokens",
+			},
+			config.KeyTimeout: {
+				Default:     "5s",
+				Required:    false,
+				Description: "timeout of every request attempt to zendesk",
+			},
+			config.KeyProxyURL: {
+				Default:     "",
+				Required:    false,
+				Description: "http, https or socks5 proxy url, the proxy environment variables are used if empty",
+			},
+			config.KeyTLSCAFile: {
+				Default:     "",
+				Required:    false,
+				Description: "PEM bundle of the CAs trusted in addition to the system ones",
+			},
+			config.KeyTLSCertFile: {
+				Default:     "",
+				Required:    false,
+				Description: "PEM client certificate, for mutual TLS",
+			},
+			config.KeyTLSKeyFile: {
+				Default:     "",
+				Required:    false,
+				Description: "PEM private key of the client certificate",
+			},
+			config.KeyTLSMinVersion: {
+				Default:     "1.2",
+				Required:    false,
+				Description: "minimum TLS version, one of `1.0`, `1.1`, `1.2` or `1.3`",
 			},
 			source.KeyPollingPeriod: {
 				Default:     "6s",
 				Required:    false,
 				Description: "Fetch interval for consecutive iterations",
 			},
+			source.KeyEntities: {
+				Default:     "tickets",
+				Required:    false,
+				Description: "comma separated list of zendesk entities to be read, supported: `tickets`, `users`, `organizations`, `satisfaction_ratings`, `articles`",
+			},
+			source.KeySnapshot: {
+				Default:     "false",
+				Required:    false,
+				Description: "read the existing objects as snapshot, before switching to CDC mode",
+			},
+			source.KeyFilter: {
+				Default:     "",
+				Required:    false,
+				Description: "expression the objects must match to be read, i.e. `brand_id in (1, 2) and status != \"closed\"`, every object is read if empty",
+			},
+			source.KeyFieldsInclude: {
+				Default:     "",
+				Required:    false,
+				Description: "comma separated list of the payload fields kept, nested fields are separated with dots, every field is kept if empty",
+			},
+			source.KeyFieldsExclude: {
+				Default:     "",
+				Required:    false,
+				Description: "comma separated list of the payload fields removed, nested fields are separated with dots",
+			},
+			source.KeyFieldsRedact: {
+				Default:     "",
+				Required:    false,
+				Description: "comma separated list of `field:action` redaction rules, the action is one of `drop`, `hash` or `mask`",
+			},
+			source.KeyFieldsHashSalt: {
+				Default:     "",
+				Required:    false,
+				Description: "salt of the hash redaction rules, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			source.KeySearchQuery: {
+				Default:     "",
+				Required:    false,
+				Description: "zendesk search query of the objects read with the search export api instead of the entities, i.e. `tags:vip`",
+			},
+			source.KeySearchType: {
+				Default:     "ticket",
+				Required:    false,
+				Description: "type of the objects searched, one of ticket, user, organization or group",
+			},
+			source.KeyWebhookAddress: {
+				Default:     "",
+				Required:    false,
+				Description: "address of the server receiving the objects posted by zendesk webhooks, i.e. `:8080`, the exports are only polled if empty",
+			},
+			source.KeyWebhookPath: {
+				Default:     "/",
+				Required:    false,
+				Description: "path prefix of the webhook routes, the objects of an entity are posted to `<path>/<entity>`",
+			},
+			source.KeyWebhookSecret: {
+				Default:     "",
+				Required:    false,
+				Description: "signing secret verifying the webhook requests, required in webhook mode, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			source.KeyWebhookObjectField: {
+				Default:     "",
+				Required:    false,
+				Description: "dotted path of the object in the webhook request body, i.e. `ticket`, the body is the object if empty",
+			},
+			source.KeyWebhookTolerance: {
+				Default:     "5m",
+				Required:    false,
+				Description: "maximum age of the signature timestamp of the webhook requests",
+			},
+			source.KeyWebhookSweepPeriod: {
+				Default:     "15m",
+				Required:    false,
+				Description: "period of the incremental exports reconciling the objects missed by the webhook, replacing the polling period",
+			},
+			source.KeyDedupWindow: {
+				Default:     "0",
+				Required:    false,
+				Description: "number of the last emitted objects remembered in the position of each entity, to drop the records of the objects already emitted with the same update time, disabled if 0",
+			},
+			source.KeyChangesFields: {
+				Default:     "",
+				Required:    false,
+				Description: "comma separated list of the tracked fields, nested fields separated with dots, records are only emitted when one of them changed",
+			},
+			source.KeyChangesStorePath: {
+				Default:     "",
+				Required:    false,
+				Description: "path of the local file storing the hashes of the tracked fields, required with changes.fields",
+			},
+			source.KeySchemaInfer: {
+				Default:     "false",
+				Required:    false,
+				Description: "infer the schema of the tickets from the ticket fields, set its fingerprint in the metadata of the tickets and emit a schema change record whenever it changes",
+			},
+			source.KeyTicketFieldsRefreshPeriod: {
+				Default:     "15m",
+				Required:    false,
+				Description: "period at which the ticket fields are fetched again, with schema.infer or customFields.flatten",
+			},
+			source.KeyCustomFieldsFlatten: {
+				Default:     "false",
+				Required:    false,
+				Description: "flatten the custom_fields array of the tickets into an object keyed by field title or alias, with the values coerced to the type of their field",
+			},
+			source.KeyCustomFieldsAliases: {
+				Default:     "",
+				Required:    false,
+				Description: "comma separated list of id:alias keys of the flattened custom fields, replacing their title",
+			},
+			source.KeyAccounts: {
+				Default:     "",
+				Required:    false,
+				Description: "comma separated list of the subdomains of the accounts read along with each other, instead of the domain, the zendesk configs of an account are set with accounts.<subdomain>.<config>",
+			},
+			source.KeyStartTime: {
+				Default:     "",
+				Required:    false,
+				Description: "RFC3339 time from which the objects of the entities without position are read, they are read from the beginning if empty",
+			},
+			source.KeyStartFrom: {
+				Default:     "",
+				Required:    false,
+				Description: "time from which the objects of the entities without position are read, a RFC3339 time, a negative duration like `-720h` or `now`, they are read from the beginning if empty",
+			},
+			source.KeyEndTime: {
+				Default:     "",
+				Required:    false,
+				Description: "RFC3339 time from which the updated objects aren't read, the source stops reading once every entity reaches it, it reads without end if empty",
+			},
+			source.KeyBackfillStart: {
+				Default:     "",
+				Required:    false,
+				Description: "RFC3339 start time of the parallel backfill of the entities without position, the snapshot is used if empty",
+			},
+			source.KeyBackfillEnd: {
+				Default:     "",
+				Required:    false,
+				Description: "RFC3339 end time of the backfill, from which the entities are read in CDC mode, the time the source is opened if empty",
+			},
+			source.KeyBackfillSlices: {
+				Default:     "8",
+				Required:    false,
+				Description: "number of time slices the backfill is split into",
+			},
+			source.KeyBackfillConcurrency: {
+				Default:     "4",
+				Required:    false,
+				Description: "number of backfill slices read in parallel",
+			},
 		},
 		DestinationParams: map[string]sdk.Parameter{
 			config.KeyDomain: {
 				Default:     "",
-				Required:    true,
-				Description: "A domain is referred as the organization name to which zendesk is registered",
+				Required:    false,
+				Description: "A domain is referred as the organization name to which zendesk is registered, required unless the base url is set",
+			},
+			config.KeyBaseURL: {
+				Default:     "",
+				Required:    false,
+				Description: "zendesk api url, overrides the url derived from the domain, i.e. for sandboxes, proxies or local servers",
+			},
+			config.KeyAuthType: {
+				Default:     "basic",
+				Required:    false,
+				Description: "authentication type, either basic (username and api token) or oauth",
 			},
 			config.KeyUserName: {
 				Default:     "",
-				Required:    true,
-				Description: "Login to zendesk performed using username",
+				Required:    false,
+				Description: "Login to zendesk performed using username, required for basic authentication",
 			},
 			config.KeyAPIToken: {
 				Default:     "",
-				Required:    true,
-				Description: "password to login",
+				Required:    false,
+				Description: "password to login, required for basic authentication, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			config.KeyOAuthAccessToken: {
+				Default:     "",
+				Required:    false,
+				Description: "OAuth access token, used as is for oauth authentication, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			config.KeyOAuthClientID: {
+				Default:     "",
+				Required:    false,
+				Description: "OAuth client id, used to request access tokens when no access token is set",
+			},
+			config.KeyOAuthClientSecret: {
+				Default:     "",
+				Required:    false,
+				Description: "OAuth client secret, required along with the client id, may reference a `${file:<path>}` or `${env:<variable>}` secret",
+			},
+			config.KeyOAuthRefreshToken: {
+				Default:     "",
+				Required:    false,
+				Description: "`${file:<path>}` reference of a writable file holding the OAuth refresh token, used to renew the access tokens instead of the client credentials grant, the rotated refresh token is written back to it",
+			},
+			config.KeyOAuthScope: {
+				Default:     "read write",
+				Required:    false,
+				Description: "OAuth scope of the requested access tokens",
+			},
+			config.KeyTimeout: {
+				Default:     "5s",
+				Required:    false,
+				Description: "timeout of every request attempt to zendesk",
+			},
+			config.KeyProxyURL: {
+				Default:     "",
+				Required:    false,
+				Description: "http, https or socks5 proxy url, the proxy environment variables are used if empty",
+			},
+			config.KeyTLSCAFile: {
+				Default:     "",
+				Required:    false,
+				Description: "PEM bundle of the CAs trusted in addition to the system ones",
+			},
+			config.KeyTLSCertFile: {
+				Default:     "",
+				Required:    false,
+				Description: "PEM client certificate, for mutual TLS",
+			},
+			config.KeyTLSKeyFile: {
+				Default:     "",
+				Required:    false,
+				Description: "PEM private key of the client certificate",
+			},
+			config.KeyTLSMinVersion: {
+				Default:     "1.2",
+				Required:    false,
+				Description: "minimum TLS version, one of `1.0`, `1.1`, `1.2` or `1.3`",
 			},
 			destination.KeyBufferSize: {
 				Default:     "100",
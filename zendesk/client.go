@@ -0,0 +1,328 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/config"
+)
+
+const (
+	defaultRetryAfter = 93 // cool off duration in seconds, when zendesk doesn't send `Retry-After` with 429
+
+	defaultTimeout    = 5 * time.Second        // timeout of the requests, unless configured
+	defaultMaxRetries = 3                      // retries on 5xx and network errors, before returning an error
+	defaultRetryDelay = 500 * time.Millisecond // base delay for the exponential backoff between retries
+)
+
+// Middleware wraps the round tripper of the client, to add behavior to every request made to zendesk
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Client is the http client shared by the source and destination to connect zendesk
+type Client struct {
+	baseURL string       // zendesk api url
+	client  *http.Client // http client, with the middleware chain as transport
+}
+
+// RateLimitError is returned when zendesk responds with 429, RetryAfter is the cool off duration before retrying
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate-limit exceeded, retry after %v", e.RetryAfter)
+}
+
+// StatusError is returned when zendesk responds with a non 2xx status code, other than 429
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("non 200 status code(%d) received(%s)", e.StatusCode, string(e.Body))
+}
+
+// NewClient returns a client for the zendesk api url, the middlewares are applied to every request in the given order
+func NewClient(baseURL string, middlewares ...Middleware) *Client {
+	return newClient(baseURL, http.DefaultTransport, middlewares...)
+}
+
+// newClient returns a client sending the requests through the transport, once they went through the middlewares
+func newClient(baseURL string, transport http.RoundTripper, middlewares ...Middleware) *Client {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Transport: transport},
+	}
+}
+
+// NewAccountClient returns the client used to connect the zendesk account at the config url,
+// with logging, retries, shared rate limiting, authentication and request timeout middlewares,
+// over a transport with the TLS and proxy settings of the config
+func NewAccountClient(cfg config.Config) (*Client, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	baseURL := cfg.URL()
+	auth, credential := authMiddleware(baseURL, cfg, &http.Client{Transport: transport, Timeout: timeout})
+	return newClient(
+		baseURL,
+		transport,
+		Logging(),
+		Retry(defaultMaxRetries, defaultRetryDelay),
+		RateLimit(SharedRateLimiter(baseURL, credential)),
+		auth,
+		Timeout(timeout),
+	), nil
+}
+
+// Get performs a GET request, the url can either be absolute, or a path relative to the zendesk api url
+func (c *Client) Get(ctx context.Context, url string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, url, nil)
+}
+
+// Post performs a POST request with the JSON body, the url can either be absolute, or a path relative to the zendesk api url
+func (c *Client) Post(ctx context.Context, url string, body []byte) ([]byte, error) {
+	return c.do(ctx, http.MethodPost, url, body)
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	if strings.HasPrefix(url, "/") {
+		url = c.baseURL + url
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("could not access the zendesk: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the zendesk response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// no use checking the error, if it errors, we will just have empty body in error
+	respBody, err := ioutil.ReadAll(resp.Body)
+
+	// Validation for httpStatusCode 429 - Too many Requests, Retry value after `93s`
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{RetryAfter: retryAfterDuration(resp)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: respBody}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the response body: %w", err)
+	}
+	return respBody, nil
+}
+
+// BasicAuth authenticates the requests using the zendesk api token.
+// The api token may reference a secret, resolved for every request so a rotated secret is picked up.
+func BasicAuth(userName, apiToken string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			token, err := config.ResolveSecret(apiToken)
+			if err != nil {
+				return nil, fmt.Errorf("could not get the api token: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Basic "+basicAuth(userName, token))
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RateLimit paces the requests using the rate limiter, and blocks all the requests sharing
+// the rate limiter for the `Retry-After` duration, once zendesk responds with 429
+func RateLimit(limiter *RateLimiter) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			limiter.Update(resp)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				limiter.Block(retryAfterDuration(resp))
+			}
+			return resp, nil
+		})
+	}
+}
+
+// Retry retries the idempotent requests failing with network errors or 5xx status codes,
+// with exponential backoff and jitter between the attempts. Other requests, like the imports,
+// are only retried if they failed before being sent, as zendesk may have processed them otherwise.
+func Retry(maxRetries int, baseDelay time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			for attempt := 0; ; attempt++ {
+				attemptReq := req
+				if attempt > 0 && req.Body != nil {
+					if req.GetBody == nil {
+						return nil, fmt.Errorf("unable to retry the request, body can't be replayed")
+					}
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, fmt.Errorf("unable to retry the request: %w", err)
+					}
+					attemptReq = req.Clone(req.Context())
+					attemptReq.Body = body
+				}
+
+				resp, err := next.RoundTrip(attemptReq)
+				if !retryable(req, resp, err) || attempt >= maxRetries || req.Context().Err() != nil {
+					return resp, err
+				}
+				if resp != nil {
+					_, _ = io.Copy(ioutil.Discard, resp.Body)
+					resp.Body.Close()
+				}
+
+				select {
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				case <-time.After(backoff(baseDelay, attempt)):
+				}
+			}
+		})
+	}
+}
+
+// retryable returns true if the attempt of the request can be sent again
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return err != nil || resp.StatusCode >= http.StatusInternalServerError
+	default:
+		// failing to connect, e.g. a refused connection, is the only failure guaranteeing zendesk didn't receive the request
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+}
+
+// backoff returns the exponential delay for the attempt, with +/-50% jitter
+func backoff(baseDelay time.Duration, attempt int) time.Duration {
+	delay := baseDelay << attempt
+	jitter := 0.5 + rand.Float64() //nolint:gosec // jitter doesn't need a secure random number
+	return time.Duration(float64(delay) * jitter)
+}
+
+// Logging logs every request made to zendesk, with the status code and duration
+func Logging() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			logger := sdk.Logger(req.Context()).Trace().
+				Str("method", req.Method).
+				Str("path", req.URL.Path).
+				Dur("duration", time.Since(start))
+			if err != nil {
+				logger.Err(err).Msg("zendesk request failed")
+				return nil, err
+			}
+			logger.Int("status", resp.StatusCode).Msg("zendesk request completed")
+			return resp, nil
+		})
+	}
+}
+
+// Timeout limits the duration of every request attempt, including reading the response body
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+// cancelOnClose releases the context of the request, once the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// retryAfterDuration returns the cool off duration received in the `Retry-After` header of a 429 response,
+// defaults to 93s if the header is missing or invalid
+// NOTE: https://developer.zendesk.com/documentation/ticketing/using-the-zendesk-api/best-practices-for-avoiding-rate-limiting/#catching-errors-caused-by-rate-limiting
+func retryAfterDuration(resp *http.Response) time.Duration {
+	retryValue, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64)
+	if err != nil || retryValue <= 0 {
+		retryValue = defaultRetryAfter
+	}
+	return time.Duration(retryValue) * time.Second
+}
+
+func basicAuth(username, apiToken string) string {
+	auth := username + "/token:" + apiToken
+	return base64.StdEncoding.EncodeToString([]byte(auth))
+}
@@ -0,0 +1,278 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Get(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/tickets.json", r.URL.Path)
+		assert.Equal(t, "Basic "+basicAuth("dummy_user", "dummy_token"), r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"tickets":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, BasicAuth("dummy_user", "dummy_token"))
+	body, err := client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"tickets":[]}`, string(body))
+
+	// absolute urls are used as is
+	body, err = client.Get(context.Background(), server.URL+"/api/v2/tickets.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"tickets":[]}`, string(body))
+}
+
+func TestBasicAuth_SecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-token")
+	assert.NoError(t, os.WriteFile(path, []byte("dummy_token\n"), 0o600))
+
+	var want atomic.Value
+	want.Store("Basic " + basicAuth("dummy_user", "dummy_token"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, want.Load(), r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"tickets":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, BasicAuth("dummy_user", "${file:"+path+"}"))
+	_, err := client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.NoError(t, err)
+
+	// the rotated token is used once the file changes
+	assert.NoError(t, os.WriteFile(path, []byte("rotated_token\n"), 0o600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	want.Store("Basic " + basicAuth("dummy_user", "rotated_token"))
+	_, err = client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.NoError(t, err)
+}
+
+func TestNewAccountClient_BaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/proxy/api/v2/tickets.json", r.URL.Path)
+		_, _ = w.Write([]byte(`{"tickets":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewAccountClient(config.Config{Domain: "testlab", BaseURL: server.URL + "/proxy", UserName: "dummy_user", APIToken: "dummy_token"})
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/proxy", client.baseURL)
+	_, err = client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.NoError(t, err)
+
+	client, err = NewAccountClient(config.Config{Domain: "testlab"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://testlab.zendesk.com", client.baseURL)
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		header     map[string]string
+		want       error
+	}{
+		{
+			name:       "429 with Retry-After",
+			statusCode: http.StatusTooManyRequests,
+			header:     map[string]string{"Retry-After": "10"},
+			want:       &RateLimitError{RetryAfter: 10 * time.Second},
+		},
+		{
+			name:       "429 without Retry-After",
+			statusCode: http.StatusTooManyRequests,
+			want:       &RateLimitError{RetryAfter: defaultRetryAfter * time.Second},
+		},
+		{
+			name:       "404",
+			statusCode: http.StatusNotFound,
+			want:       &StatusError{StatusCode: http.StatusNotFound, Body: []byte("not found")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, val := range tt.header {
+					w.Header().Set(key, val)
+				}
+				w.WriteHeader(tt.statusCode)
+				if tt.statusCode == http.StatusNotFound {
+					_, _ = w.Write([]byte("not found"))
+				}
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL).Get(context.Background(), "/api/v2/tickets.json")
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, Retry(3, time.Millisecond))
+	_, err := client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetry_PostNotSent(t *testing.T) {
+	var calls int32
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"tickets":[]}`, string(body)) // body is replayed on every attempt
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("{}"))}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.zendesk.com/api/v2/imports/tickets/create_many", strings.NewReader(`{"tickets":[]}`))
+	assert.NoError(t, err)
+	resp, err := Retry(3, time.Millisecond)(transport).RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetry_PostNotSentAgain(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// zendesk keeps processing the request once the client gave up
+				_, _ = ioutil.ReadAll(r.Body)
+				<-r.Context().Done()
+			},
+		},
+		{
+			name: "5xx",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				tt.handler(w, r)
+			}))
+
+			client := NewClient(server.URL, Retry(3, time.Millisecond), Timeout(10*time.Millisecond))
+			_, err := client.Post(context.Background(), "/api/v2/imports/tickets/create_many", []byte(`{"tickets":[]}`))
+			assert.Error(t, err)
+
+			server.Close() // waits for the requests being handled
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestRetry_MaxRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, Retry(2, time.Millisecond))
+	_, err := client.Get(context.Background(), "/api/v2/tickets.json")
+	var statusErr *StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetry_NotOn4xx(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, Retry(2, time.Millisecond))
+	_, err := client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimit_BlocksAfter429(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter()
+	client := NewClient(server.URL, RateLimit(limiter))
+	_, err := client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.Equal(t, &RateLimitError{RetryAfter: time.Minute}, err)
+
+	// the next request waits for the cool off duration
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.Get(ctx, "/api/v2/tickets.json")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, Timeout(10*time.Millisecond))
+	_, err := client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -18,12 +18,10 @@ package zendesk
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/conduitio/conduit-connector-zendesk/source/position"
@@ -32,151 +30,417 @@ import (
 )
 
 type Cursor struct {
-	client           *http.Client // new http client
-	userName         string       // zendesk username
-	apiToken         string       // zendesk apiToken
-	afterURL         string       // index url for next fetch of tickets
-	nextRun          time.Time    // configurable polling period to hit zendesk api
-	lastModifiedTime time.Time    // ticket last updated time
-	baseURL          string       // zendesk api url
+	export
+	afterURL         string               // index url for next fetch of entity objects
+	lastModifiedTime time.Time            // entity object last updated time
+	seenIDs          map[float64]struct{} // ids of the objects read with the last modified time, to skip them if returned again
+	inclusiveStart   bool                 // restart the export at the last modified time, instead of the next second
 }
 
+// export holds the state shared by the cursors of the exports, time based or search, along with the requests of their pages
+type export struct {
+	client        *Client   // zendesk http client
+	entity        Entity    // descriptor of the entity being exported
+	nextRun       time.Time // configurable polling period to hit zendesk api
+	snapshotEnd   time.Time // time at which the snapshot started, zero once the cursor is in CDC mode
+	markCompleted bool      // the snapshot completed without records, mark the next record instead
+	endTime       time.Time // objects updated from the end time on are not read, zero to read without end
+	done          bool      // the objects updated till the end time are read
+	more          bool      // the last fetch read a page of the export, which isn't the last one
+}
+
+// record metadata keys set during the snapshot
+const (
+	// MetadataSnapshot is set to "true" for records read during the snapshot
+	MetadataSnapshot = "snapshot"
+	// MetadataSnapshotCompleted is set to "true" for the record at which the cursor switched from snapshot to CDC mode
+	MetadataSnapshotCompleted = "snapshot_completed"
+)
+
 type response struct {
-	AfterURL    *string                  `json:"after_url"`     // index for to fetch next list of tickets
-	EndOfStream bool                     `json:"end_of_stream"` // boolean to indicate end of ticket fetch
-	TicketList  []map[string]interface{} `json:"tickets"`       // stores list of tickets
+	AfterURL    *string `json:"after_url"`     // index for to fetch next list of objects, in cursor based exports
+	NextPage    *string `json:"next_page"`     // url to fetch next page of objects, in time based exports
+	EndOfStream bool    `json:"end_of_stream"` // boolean to indicate end of objects fetch
+	Meta        struct {
+		HasMore bool `json:"has_more"` // more results are left, in search exports
+	} `json:"meta"`
+	Links struct {
+		Next *string `json:"next"` // url to fetch next page of results, in search exports
+	} `json:"links"`
 }
 
-func NewCursor(userName, apiToken, domain string, startTime time.Time) *Cursor {
+// page is a page of an export, along with the list of its objects
+type page struct {
+	response
+	list []map[string]interface{}
+}
+
+// errExpiredAfterURL is returned when the url of the next page is rejected, the export is restarted from the last modified time
+var errExpiredAfterURL = errors.New("after url rejected")
+
+// NewCursor returns the cursor exporting the entity objects updated after the start time, using the zendesk client
+func NewCursor(client *Client, entity Entity, startTime time.Time) *Cursor {
 	return &Cursor{
-		client:           newHTTPClient(),
-		userName:         userName,
-		apiToken:         apiToken,
-		baseURL:          fmt.Sprintf("https://%s.zendesk.com", domain),
+		export:           export{client: client, entity: entity},
 		lastModifiedTime: startTime,
 	}
 }
 
-// FetchRecords will export tickets from zendesk api, initial start_time is set to 0
+// StartSnapshot puts the cursor in snapshot mode, objects updated till the snapshot end time are flagged as snapshot records.
+// The cursor switches to CDC mode once the end of the export stream is reached.
+func (e *export) StartSnapshot(end time.Time) {
+	e.snapshotEnd = end
+}
+
+// SetEndTime bounds the export to the objects updated before the end time, the cursor is done once it reaches
+// an object updated later, or the end of the export stream requested from the end time on
+func (e *export) SetEndTime(end time.Time) {
+	e.endTime = end
+}
+
+// ResumeInclusive restarts the export at the last modified time, instead of the next second, so the objects updated
+// in the same second as the last one read aren't missed. The objects with the ids, already read at that time, are skipped.
+func (c *Cursor) ResumeInclusive(ids []float64) {
+	c.inclusiveStart = true
+	c.seenIDs = make(map[float64]struct{}, len(ids))
+	for _, id := range ids {
+		c.seenIDs[id] = struct{}{}
+	}
+}
+
+// Done reports whether the cursor read every object updated before the end time, always false without end time
+func (e *export) Done() bool {
+	return e.done
+}
+
+// More reports whether the last fetch read a page of the export followed by more pages, which can be fetched right away
+func (e *export) More() bool {
+	return e.more
+}
+
+// FetchRecords will export the entity objects from zendesk api, initial start_time is set to 0
 func (c *Cursor) FetchRecords(ctx context.Context) ([]sdk.Record, error) {
-	if c.nextRun.After(time.Now()) {
+	requested := time.Now()
+	c.more = false
+	if c.done || c.nextRun.After(requested) {
 		return nil, nil
 	}
 
-	url := fmt.Sprintf("%s/api/v2/incremental/tickets/cursor.json?start_time=%d", c.baseURL, c.lastModifiedTime.Add(time.Second).Unix()) // add one extra second, to get newer updates only
+	startTime := c.lastModifiedTime.Add(time.Second) // add one extra second, to get newer updates only
+	if c.inclusiveStart {
+		// the objects of the last modified time, which are already read, are skipped using the seen ids
+		startTime = c.lastModifiedTime
+	}
+	url := fmt.Sprintf("%s?start_time=%d", c.entity.Endpoint, startTime.Unix())
 
 	// if after URL is available, use that
 	if c.afterURL != "" {
 		url = c.afterURL
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("could not access the zendesk: %w", err)
+	p, err := c.get(ctx, url, c.afterURL != "")
+	if errors.Is(err, errExpiredAfterURL) {
+		c.logRestart(ctx, err, c.lastModifiedTime)
+		c.afterURL = ""
+		c.inclusiveStart = true
+		return nil, nil
+	}
+	if err != nil || p == nil {
+		return nil, err
 	}
-	req.Header.Add("Authorization", "Basic "+basicAuth(c.userName, c.apiToken))
 
-	resp, err := c.client.Do(req)
+	records, err := c.toRecords(p.list)
 	if err != nil {
-		return nil, fmt.Errorf("could not get the zendesk response: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Validation for httpStatusCode 429 - Too many Requests, Retry value after `93s`
-	if resp.StatusCode == http.StatusTooManyRequests {
-		// NOTE: https://developer.zendesk.com/documentation/ticketing/using-the-zendesk-api/best-practices-for-avoiding-rate-limiting/#catching-errors-caused-by-rate-limiting
-		retryValue, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64)
-		if err != nil {
-			return nil, fmt.Errorf("unable to get retry value: %w", err)
+	c.inclusiveStart = false
+	switch c.entity.Pagination {
+	case PaginationCursor:
+		if p.AfterURL != nil {
+			c.afterURL = *p.AfterURL
 		}
+	case PaginationTime:
+		// time based exports can keep returning next_page for an empty page,
+		// restart from the last modified time in the next poll, once the last page is read
+		c.afterURL = ""
+		if p.NextPage != nil && len(p.list) > 0 && !p.EndOfStream {
+			c.afterURL = *p.NextPage
+		}
+	}
 
+	endOfStream := p.EndOfStream || (c.entity.Pagination != PaginationCursor && c.afterURL == "")
+	c.more = !endOfStream && c.afterURL != ""
+	c.completePage(ctx, requested, endOfStream, records)
+	return records, nil
+}
+
+// get requests the page of the export at the url. It returns a nil page when the request failed and is tried again
+// in the next poll, and errExpiredAfterURL when the url of the next page, if it is one, can't be used anymore.
+func (e *export) get(ctx context.Context, url string, afterURL bool) (*page, error) {
+	body, err := e.client.Get(ctx, url)
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
 		// skip hitting API till retry_after duration passes
-		c.nextRun = time.Now().Add(time.Duration(retryValue) * time.Second)
+		e.nextRun = time.Now().Add(rateLimitErr.RetryAfter)
 		return nil, nil
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non 200 status code received(%v)", resp.StatusCode)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode >= http.StatusInternalServerError:
+			// the client already retried, try again in the next poll
+			e.logTransient(ctx, err)
+			return nil, nil
+		case afterURL && isExpiredCursor(statusErr.StatusCode):
+			return nil, fmt.Errorf("%w: status %d", errExpiredAfterURL, statusErr.StatusCode)
+		}
+		return nil, fmt.Errorf("non 200 status code received(%v)", statusErr.StatusCode)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		// network errors and timeouts, the client already retried, try again in the next poll
+		e.logTransient(ctx, err)
+		return nil, nil
 	}
 
-	ticketList, err := ioutil.ReadAll(resp.Body)
+	var p page
+	err = json.Unmarshal(body, &p.response)
+	if err != nil {
+		// truncated or malformed response, the cursor isn't moved, so the same page is requested in the next poll
+		e.logTransient(ctx, fmt.Errorf("error unmarshaling the response body: %w", err))
+		return nil, nil
+	}
+	p.list, err = e.parseList(body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading the response body: %w", err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+// completePage completes the snapshot, and the export bounded by an end time, once the end of the export stream is reached
+func (e *export) completePage(ctx context.Context, requested time.Time, endOfStream bool, records []sdk.Record) {
+	if !e.snapshotEnd.IsZero() && endOfStream {
+		e.completeSnapshot(ctx, records)
+	}
+	// objects can still be updated before an end time yet to come
+	if !e.endTime.IsZero() && endOfStream && !requested.Before(e.endTime) {
+		e.done = true
+	}
+}
+
+// logTransient logs the failure of a request, which is retried in the next poll
+func (e *export) logTransient(ctx context.Context, err error) {
+	sdk.Logger(ctx).Warn().
+		Err(err).
+		Str("entity", e.entity.Name).
+		Msg("failed to fetch records, retrying in the next poll")
+}
+
+// logRestart logs the restart of the export from the last modified time, once the url of the next page is rejected
+func (e *export) logRestart(ctx context.Context, err error, lastModifiedTime time.Time) {
+	sdk.Logger(ctx).Warn().
+		Err(err).
+		Str("entity", e.entity.Name).
+		Time("last_modified_time", lastModifiedTime).
+		Msg("after url rejected, restarting the export from the last modified time")
+}
+
+// isExpiredCursor reports whether the status code of a rejected after url means the url can't be used anymore,
+// as opposed to authentication errors, which would fail the same way after restarting the export
+func isExpiredCursor(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusGone, http.StatusUnprocessableEntity:
+		return true
+	default:
+		return false
+	}
+}
+
+// completeSnapshot switches the cursor to CDC mode and marks the last snapshot record
+func (e *export) completeSnapshot(ctx context.Context, records []sdk.Record) {
+	sdk.Logger(ctx).Info().
+		Str("entity", e.entity.Name).
+		Time("snapshot_end", e.snapshotEnd).
+		Msg("snapshot completed, switching to CDC mode")
+
+	e.snapshotEnd = time.Time{}
+	if len(records) == 0 {
+		e.markCompleted = true
+		return
 	}
+	records[len(records)-1].Metadata[MetadataSnapshotCompleted] = "true"
+}
 
-	var res response
-	err = json.Unmarshal(ticketList, &res)
+// parseList extracts the list of entity objects from the response body
+func (e *export) parseList(body []byte) ([]map[string]interface{}, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(body, &fields)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshaling the response body: %w", err)
 	}
 
-	if res.AfterURL != nil {
-		c.afterURL = *res.AfterURL
+	raw, ok := fields[e.entity.ListField]
+	if !ok {
+		return nil, nil
 	}
-
-	return c.toRecords(res.TicketList)
+	var list []map[string]interface{}
+	err = json.Unmarshal(raw, &list)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling the %s list: %w", e.entity.ListField, err)
+	}
+	return list, nil
 }
 
-// convert received ticket list to sdk.Record
-func (c *Cursor) toRecords(tickets []map[string]interface{}) ([]sdk.Record, error) {
-	records := make([]sdk.Record, 0, len(tickets))
+// convert received entity object list to sdk.Record
+func (c *Cursor) toRecords(objects []map[string]interface{}) ([]sdk.Record, error) {
+	records := make([]sdk.Record, 0, len(objects))
 	lastValidModifiedTime := c.lastModifiedTime
-	for _, ticket := range tickets {
-		payload, err := json.Marshal(ticket)
+	seenIDs := make(map[float64]struct{}, len(c.seenIDs))
+	for id := range c.seenIDs {
+		seenIDs[id] = struct{}{}
+	}
+	for _, object := range objects {
+		id, updatedAt, createdAt, err := c.parseObject(object, lastValidModifiedTime)
 		if err != nil {
-			return nil, fmt.Errorf("error marshaling the payload: %w", err)
+			return nil, err
 		}
 
-		id, ok := ticket["id"].(float64)
-		if !ok {
-			return nil, fmt.Errorf("invalid type of id encountered: %T", ticket["id"])
-		}
-		updatedAt, err := time.Parse(time.RFC3339, ticket["updated_at"].(string))
-		if err != nil {
-			return nil, fmt.Errorf("invalid time in updated_at field: %w", err)
-		}
-		createdAt, err := time.Parse(time.RFC3339, ticket["created_at"].(string))
-		if err != nil {
-			return nil, fmt.Errorf("invalid time in created_at field: %w", err)
+		// the objects are exported in update order, the following ones are updated after the end time too
+		if !c.endTime.IsZero() && !updatedAt.Before(c.endTime) {
+			c.done = true
+			break
 		}
 
-		// there were a few records from zendesk, which had both created_at and updated_at set to 1970-01-01T00:00:00Z
-		// handle such case, to ensure we don't start pulling all the records after the pause
-		if updatedAt.IsZero() {
-			if createdAt.IsZero() || createdAt.Before(lastValidModifiedTime) {
-				updatedAt = lastValidModifiedTime
-			} else {
-				updatedAt = createdAt
-			}
+		// zendesk can return the same object again, i.e. across pages, or after restarting the export at the last modified time
+		if _, ok := seenIDs[id]; ok && updatedAt.Equal(lastValidModifiedTime) {
+			continue
 		}
-
 		if updatedAt.After(lastValidModifiedTime) {
 			lastValidModifiedTime = updatedAt
+			seenIDs = make(map[float64]struct{})
+		}
+		if updatedAt.Equal(lastValidModifiedTime) {
+			seenIDs[id] = struct{}{}
 		}
 
-		toRecordPosition, err := (&position.TicketPosition{LastModified: updatedAt, ID: id}).ToRecordPosition()
+		record, err := c.toRecord(object, id, updatedAt, createdAt, position.EntityPosition{LastModified: updatedAt, ID: id})
 		if err != nil {
 			return nil, err
 		}
-
-		records = append(records, sdk.Record{
-			Position:  toRecordPosition,
-			Metadata:  nil,
-			CreatedAt: createdAt,
-			Key:       sdk.RawData(fmt.Sprintf("%v", id)),
-			Payload:   sdk.RawData(payload),
-		})
+		records = append(records, record)
 	}
+	c.lastModifiedTime = lastValidModifiedTime
+	c.seenIDs = seenIDs
 	return records, nil
 }
 
-func basicAuth(username, apiToken string) string {
-	auth := username + "/token:" + apiToken
-	return base64.StdEncoding.EncodeToString([]byte(auth))
+// Record converts an object of the entity received outside of the export, i.e. by a webhook, to a record positioned at the object
+func (c *Cursor) Record(object map[string]interface{}) (sdk.Record, error) {
+	id, updatedAt, createdAt, err := c.parseObject(object, time.Time{})
+	if err != nil {
+		return sdk.Record{}, err
+	}
+	return c.toRecord(object, id, updatedAt, createdAt, position.EntityPosition{LastModified: updatedAt, ID: id})
+}
+
+// parseObject returns the id, update and creation times of the entity object
+func (e *export) parseObject(object map[string]interface{}, lastModifiedTime time.Time) (float64, time.Time, time.Time, error) {
+	id, ok := object[e.entity.IDField].(float64)
+	if !ok {
+		return 0, time.Time{}, time.Time{}, fmt.Errorf("invalid type of %s encountered: %T", e.entity.IDField, object[e.entity.IDField])
+	}
+	updatedAt, err := parseTime(object[e.entity.TimestampField])
+	if err != nil {
+		return 0, time.Time{}, time.Time{}, fmt.Errorf("invalid time in %s field: %w", e.entity.TimestampField, err)
+	}
+	createdAt, err := parseTime(object["created_at"])
+	if err != nil {
+		return 0, time.Time{}, time.Time{}, fmt.Errorf("invalid time in created_at field: %w", err)
+	}
+
+	// there were a few records from zendesk, which had both created_at and updated_at set to 1970-01-01T00:00:00Z
+	// handle such case, to ensure we don't start pulling all the records after the pause
+	if updatedAt.IsZero() {
+		if createdAt.IsZero() || createdAt.Before(lastModifiedTime) {
+			updatedAt = lastModifiedTime
+		} else {
+			updatedAt = createdAt
+		}
+	}
+	return id, updatedAt, createdAt, nil
+}
+
+// toRecord converts the entity object updated at the given time to a record at the position
+func (e *export) toRecord(object map[string]interface{}, id float64, updatedAt, createdAt time.Time, pos position.EntityPosition) (sdk.Record, error) {
+	metadata, err := e.toMetadata(object)
+	if err != nil {
+		return sdk.Record{}, err
+	}
+
+	payload, err := json.Marshal(object)
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("error marshaling the payload: %w", err)
+	}
+
+	if !e.snapshotEnd.IsZero() {
+		snapshotEnd := e.snapshotEnd
+		pos.SnapshotEnd = &snapshotEnd
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		if !updatedAt.After(e.snapshotEnd) {
+			metadata[MetadataSnapshot] = "true"
+		}
+	}
+	if e.markCompleted {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[MetadataSnapshotCompleted] = "true"
+		e.markCompleted = false
+	}
+
+	toRecordPosition, err := pos.ToRecordPosition()
+	if err != nil {
+		return sdk.Record{}, err
+	}
+
+	return sdk.Record{
+		Position:  toRecordPosition,
+		Metadata:  metadata,
+		CreatedAt: createdAt,
+		Key:       sdk.RawData(fmt.Sprintf("%v", id)),
+		Payload:   sdk.RawData(payload),
+	}, nil
+}
+
+// toMetadata copies the entity fields configured in the descriptor into the record metadata
+func (e *export) toMetadata(object map[string]interface{}) (map[string]string, error) {
+	if len(e.entity.Metadata) == 0 {
+		return nil, nil
+	}
+	metadata := make(map[string]string, len(e.entity.Metadata))
+	for key, field := range e.entity.Metadata {
+		switch v := object[field].(type) {
+		case string:
+			metadata[key] = v
+		case float64, bool:
+			metadata[key] = fmt.Sprintf("%v", v)
+		default:
+			return nil, fmt.Errorf("invalid type of %s encountered: %T", field, v)
+		}
+	}
+	return metadata, nil
 }
 
-func newHTTPClient() *http.Client {
-	return &http.Client{
-		Timeout: 5 * time.Second, // add timeout to ensure the Request doesn't get stuck
+// parseTime parses the RFC3339 timestamp received from zendesk
+func parseTime(v interface{}) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid type of time encountered: %T", v)
 	}
+	return time.Parse(time.RFC3339, s)
 }
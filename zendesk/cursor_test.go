@@ -40,22 +40,20 @@ func TestCursor_FetchRecords(t *testing.T) {
 	}
 	testServer := httptest.NewServer(th)
 	cursor := &Cursor{
-		userName:         th.username,
-		apiToken:         th.apiToken,
-		client:           &http.Client{},
-		baseURL:          testServer.URL,
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
 		lastModifiedTime: time.Unix(0, 0),
 	}
 	ctx := context.Background()
 	recs, err := cursor.FetchRecords(ctx)
 	assert.NoError(t, err)
 	assert.Len(t, recs, 1)
+	assert.True(t, cursor.More())
 }
 
 func TestCursor_FetchRecords_RateLimit(t *testing.T) {
 	// in case of nextRun being set later than now, no processing should occur
 	cursor := &Cursor{
-		nextRun: time.Now().Add(time.Minute),
+		export: export{nextRun: time.Now().Add(time.Minute)},
 	}
 	recs, err := cursor.FetchRecords(context.Background())
 	assert.Nil(t, err)
@@ -76,10 +74,7 @@ func TestCursor_FetchRecords_429(t *testing.T) {
 	}
 	testServer := httptest.NewServer(th)
 	cursor := &Cursor{
-		userName:         th.username,
-		apiToken:         th.apiToken,
-		client:           &http.Client{},
-		baseURL:          testServer.URL,
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
 		lastModifiedTime: time.Unix(0, 0),
 		afterURL:         fmt.Sprintf("%s/api/v2/incremental/tickets/cursor.json?cursor=some_dummy", testServer.URL),
 	}
@@ -90,6 +85,26 @@ func TestCursor_FetchRecords_429(t *testing.T) {
 	assert.GreaterOrEqual(t, cursor.nextRun.Unix(), time.Now().Add(90*time.Second).Unix())
 }
 
+func TestCursor_FetchRecords_429WithoutRetryAfter(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "start_time=1"},
+		statusCode: 429,
+		resp:       []byte(``),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	assert.GreaterOrEqual(t, cursor.nextRun.Unix(), time.Now().Add(defaultRetryAfter*time.Second-time.Second).Unix())
+}
+
 func TestCursor_FetchRecords_500(t *testing.T) {
 	th := &testHandler{
 		t:          t,
@@ -101,17 +116,313 @@ func TestCursor_FetchRecords_500(t *testing.T) {
 	}
 	testServer := httptest.NewServer(th)
 	cursor := &Cursor{
-		userName:         th.username,
-		apiToken:         th.apiToken,
-		client:           &http.Client{},
-		baseURL:          testServer.URL,
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
 		lastModifiedTime: time.Unix(0, 0),
 		afterURL:         fmt.Sprintf("%s/api/v2/incremental/tickets/cursor.json?cursor=some_dummy", testServer.URL),
 	}
 	ctx := context.Background()
 	recs, err := cursor.FetchRecords(ctx)
-	assert.EqualError(t, err, "non 200 status code received(500)")
+	// the request is retried in the next poll, from the same after url
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	assert.Equal(t, fmt.Sprintf("%s/api/v2/incremental/tickets/cursor.json?cursor=some_dummy", testServer.URL), cursor.afterURL)
+}
+
+func TestCursor_FetchRecords_401(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "start_time=1"},
+		statusCode: 401,
+		resp:       []byte(`{"error":"Couldn't authenticate you"}`),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.EqualError(t, err, "non 200 status code received(401)")
+	assert.Len(t, recs, 0)
+}
+
+func TestCursor_FetchRecords_TruncatedResponse(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "start_time=1"},
+		statusCode: 200,
+		resp:       []byte(`{"after_url":"something","tickets":[{"id":1,"updated_at":"2022-05-`),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	assert.Equal(t, "", cursor.afterURL)
+	assert.Equal(t, time.Unix(0, 0), cursor.lastModifiedTime)
+}
+
+func TestCursor_FetchRecords_ExpiredAfterURL(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "cursor=expired"},
+		statusCode: 400,
+		resp:       []byte(`{"error":"InvalidPaginationParameter"}`),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	lastModified := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
+		lastModifiedTime: lastModified,
+		seenIDs:          map[float64]struct{}{1: {}},
+		afterURL:         fmt.Sprintf("%s/api/v2/incremental/tickets/cursor.json?cursor=expired", testServer.URL),
+	}
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+
+	// the export restarts at the last modified time, skipping the ticket already read
+	th.url = &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: fmt.Sprintf("start_time=%d", lastModified.Unix())}
+	th.statusCode = 200
+	th.resp = []byte(`{"after_url":"something","end_of_stream":true,"tickets":[` +
+		`{"id":1,"updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"},` +
+		`{"id":2,"updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`)
+	recs, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, "2", string(recs[0].Key.Bytes()))
+	assert.False(t, cursor.inclusiveStart)
+	assert.Equal(t, "something", cursor.afterURL)
+}
+
+func TestCursor_toRecords_SkipsDuplicates(t *testing.T) {
+	cursor := &Cursor{
+		export:           export{entity: Tickets},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	recs, err := cursor.toRecords([]map[string]interface{}{
+		{"id": float64(1), "updated_at": "2022-05-08T05:49:55Z", "created_at": "2022-05-08T05:49:55Z"},
+		{"id": float64(2), "updated_at": "2022-05-08T05:49:56Z", "created_at": "2022-05-08T05:49:55Z"},
+		{"id": float64(2), "updated_at": "2022-05-08T05:49:56Z", "created_at": "2022-05-08T05:49:55Z"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 2)
+
+	// the last object of the previous page is returned again, along with a newer update of it
+	recs, err = cursor.toRecords([]map[string]interface{}{
+		{"id": float64(2), "updated_at": "2022-05-08T05:49:56Z", "created_at": "2022-05-08T05:49:55Z"},
+		{"id": float64(2), "updated_at": "2022-05-08T05:49:57Z", "created_at": "2022-05-08T05:49:55Z"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, "2", string(recs[0].Key.Bytes()))
+	assert.Equal(t, map[float64]struct{}{2: {}}, cursor.seenIDs)
+}
+
+func TestCursor_FetchRecords_SatisfactionRatings(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/satisfaction_ratings.json", RawQuery: "start_time=1"},
+		statusCode: 200,
+		resp:       []byte(`{"next_page":null,"satisfaction_ratings":[{"id":35436,"ticket_id":208,"score":"good","updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: SatisfactionRatings},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, "35436", string(recs[0].Key.Bytes()))
+	assert.Equal(t, map[string]string{"ticket_id": "208"}, recs[0].Metadata)
+	assert.Empty(t, cursor.afterURL)
+	assert.Equal(t, time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC), cursor.lastModifiedTime)
+}
+
+func TestCursor_FetchRecords_Articles(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/help_center/incremental/articles.json", RawQuery: "start_time=1"},
+		statusCode: 200,
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	nextPage := fmt.Sprintf("%s/api/v2/help_center/incremental/articles.json?start_time=1651988995", testServer.URL)
+	th.resp = []byte(fmt.Sprintf(`{"next_page":"%s","end_time":1651988995,"articles":[{"id":3601,"locale":"en-us","title":"Welcome","updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`, nextPage))
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Articles},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, "3601", string(recs[0].Key.Bytes()))
+	assert.Equal(t, map[string]string{"locale": "en-us"}, recs[0].Metadata)
+	assert.Equal(t, nextPage, cursor.afterURL)
+}
+
+func TestCursor_FetchRecords_TimeBasedEmptyPage(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/help_center/incremental/articles.json", RawQuery: "start_time=1651988995"},
+		statusCode: 200,
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	nextPage := fmt.Sprintf("%s/api/v2/help_center/incremental/articles.json?start_time=1651988995", testServer.URL)
+	th.resp = []byte(fmt.Sprintf(`{"next_page":"%s","articles":[]}`, nextPage))
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Articles},
+		lastModifiedTime: time.Unix(0, 0),
+		afterURL:         nextPage,
+	}
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	assert.Empty(t, cursor.afterURL) // next poll restarts from the last modified time
+}
+
+func TestCursor_toRecords_InvalidMetadata(t *testing.T) {
+	cursor := &Cursor{export: export{entity: SatisfactionRatings}, lastModifiedTime: time.Unix(0, 0)}
+	_, err := cursor.toRecords([]map[string]interface{}{{"id": float64(1), "updated_at": "2022-05-08T05:49:55Z", "created_at": "2022-05-08T05:49:55Z"}})
+	assert.EqualError(t, err, "invalid type of ticket_id encountered: <nil>")
+}
+
+func TestCursor_FetchRecords_Snapshot(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "start_time=1"},
+		statusCode: 200,
+		resp:       []byte(`{"after_url":"something","end_of_stream":true,"tickets":[{"id":1,"updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"},{"id":2,"updated_at":"2022-05-10T05:49:55Z","created_at":"2022-05-10T05:49:55Z"}]}`),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	snapshotEnd := time.Date(2022, 5, 9, 0, 0, 0, 0, time.UTC)
+	cursor.StartSnapshot(snapshotEnd)
+
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 2)
+	assert.Equal(t, map[string]string{MetadataSnapshot: "true"}, recs[0].Metadata)
+	// updated after the snapshot started, but still the last record read before switching to CDC
+	assert.Equal(t, map[string]string{MetadataSnapshotCompleted: "true"}, recs[1].Metadata)
+	assert.Contains(t, string(recs[0].Position), `"snapshot_end":"2022-05-09T00:00:00Z"`)
+	assert.True(t, cursor.snapshotEnd.IsZero())
+}
+
+func TestCursor_FetchRecords_SnapshotCompletedWithoutRecords(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "start_time=1"},
+		statusCode: 200,
+		resp:       []byte(`{"after_url":"something","end_of_stream":true,"tickets":[]}`),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	cursor := &Cursor{
+		export:           export{client: newTestClient(testServer.URL, th.username, th.apiToken), entity: Tickets},
+		lastModifiedTime: time.Unix(0, 0),
+	}
+	cursor.StartSnapshot(time.Now())
+
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	assert.True(t, cursor.snapshotEnd.IsZero())
+
+	// the first CDC record carries the marker
+	recs, err = cursor.toRecords([]map[string]interface{}{{"id": float64(1), "updated_at": "2022-05-08T05:49:55Z", "created_at": "2022-05-08T05:49:55Z"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{MetadataSnapshotCompleted: "true"}, recs[0].Metadata)
+	assert.NotContains(t, string(recs[0].Position), "snapshot_end")
+}
+
+func TestCursor_FetchRecords_EndTime(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "start_time=1"},
+		statusCode: 200,
+		resp:       []byte(`{"after_url":"something","end_of_stream":true,"tickets":[{"id":1,"updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`),
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	defer testServer.Close()
+	newCursor := func(end time.Time) *Cursor {
+		cursor := NewCursor(newTestClient(testServer.URL, th.username, th.apiToken), Tickets, time.Unix(0, 0))
+		cursor.SetEndTime(end)
+		return cursor
+	}
+
+	// the end of stream is reached past the end time
+	cursor := newCursor(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC))
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.True(t, cursor.Done())
+	assert.False(t, cursor.More())
+	recs, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+
+	// objects can still be updated before an end time yet to come
+	cursor = newCursor(time.Now().Add(time.Hour))
+	_, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, cursor.Done())
+
+	// objects updated from the end time on aren't read
+	cursor = newCursor(time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC))
+	recs, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
 	assert.Len(t, recs, 0)
+	assert.True(t, cursor.Done())
+}
+
+func TestCursor_FetchRecords_ResumeInclusive(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/incremental/tickets/cursor.json", RawQuery: "start_time=1651988995"},
+		statusCode: 200,
+		resp: []byte(`{"after_url":"something","tickets":[{"id":1,"updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"},` +
+			`{"id":2,"updated_at":"2022-05-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`),
+		username: "dummy_user",
+		apiToken: "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	defer testServer.Close()
+	cursor := NewCursor(newTestClient(testServer.URL, th.username, th.apiToken), Tickets, time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC))
+
+	// the export restarts at the last modified time, skipping the tickets already read at that time
+	cursor.ResumeInclusive([]float64{1})
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, "2", string(recs[0].Key.Bytes()))
+}
+
+// newTestClient returns a client authenticating with basic auth, without retries
+func newTestClient(baseURL, userName, apiToken string) *Client {
+	return NewClient(baseURL, RateLimit(NewRateLimiter()), BasicAuth(userName, apiToken))
 }
 
 type testHandler struct {
@@ -0,0 +1,205 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// customFieldsField is the ticket field holding the `{id, value}` pairs of the custom fields
+const customFieldsField = "custom_fields"
+
+// CustomFields flattens the `custom_fields` array of the tickets into an object keyed by field alias or title,
+// with the values coerced to the type of their field
+type CustomFields struct {
+	fields  func() []TicketField // current definitions of the ticket fields, refreshed by the caller
+	aliases map[float64]string   // key of the custom fields by id, replacing their title
+
+	mux     sync.Mutex
+	indexed []TicketField           // definitions from which the index was built
+	index   map[float64]customField // key and type of the custom fields by id
+}
+
+type customField struct {
+	key       string
+	fieldType string
+	options   map[string]string // name of the options by tag, of the tagger fields
+}
+
+// NewCustomFields returns the flattening of the custom fields, using the current definitions of the ticket fields
+func NewCustomFields(fields func() []TicketField, aliases map[float64]string) *CustomFields {
+	return &CustomFields{fields: fields, aliases: aliases}
+}
+
+// CustomFieldKeys returns the key of the custom fields by id, their alias, or their title when no other custom field
+// has the same one. The id is used for the other fields, so the keys don't depend on the order of the fields.
+func CustomFieldKeys(fields []TicketField, aliases map[float64]string) map[float64]string {
+	used := make(map[string]int)
+	for _, field := range fields {
+		if !field.Custom() {
+			continue
+		}
+		if alias, ok := aliases[field.ID]; ok {
+			used[alias]++
+		} else if field.Title != "" {
+			used[field.Title]++
+		}
+	}
+
+	keys := make(map[float64]string)
+	for _, field := range fields {
+		if !field.Custom() {
+			continue
+		}
+		key := aliases[field.ID]
+		if key == "" {
+			key = field.Title
+		}
+		if key == "" || (used[key] > 1 && aliases[field.ID] == "") {
+			key = formatID(field.ID)
+		}
+		keys[field.ID] = key
+	}
+	return keys
+}
+
+// Flatten returns the object with its `custom_fields` array replaced by an object of the values keyed by field.
+// Fields without definition, i.e. added since the last refresh, are keyed by id, with their value as is.
+// The object is returned as is if it doesn't have the array, i.e. users.
+func (c *CustomFields) Flatten(object map[string]interface{}) map[string]interface{} {
+	list, ok := object[customFieldsField].([]interface{})
+	if !ok {
+		return object
+	}
+	index := c.lookupIndex()
+
+	flattened := make(map[string]interface{}, len(list))
+	for _, item := range list {
+		pair, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := pair["id"].(float64)
+		if !ok {
+			continue
+		}
+		field, ok := index[id]
+		if !ok {
+			flattened[formatID(id)] = pair["value"]
+			continue
+		}
+		flattened[field.key] = field.coerce(pair["value"])
+	}
+
+	copied := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		copied[key] = value
+	}
+	copied[customFieldsField] = flattened
+	return copied
+}
+
+// lookupIndex returns the index of the current definitions, built again once they are refreshed
+func (c *CustomFields) lookupIndex() map[float64]customField {
+	fields := c.fields()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.index != nil && sameFields(c.indexed, fields) {
+		return c.index
+	}
+	keys := CustomFieldKeys(fields, c.aliases)
+	c.index = make(map[float64]customField, len(keys))
+	for _, field := range fields {
+		key, ok := keys[field.ID]
+		if !ok {
+			continue
+		}
+		indexed := customField{key: key, fieldType: field.Type}
+		if field.Type == "tagger" {
+			indexed.options = make(map[string]string, len(field.Options))
+			for _, option := range field.Options {
+				indexed.options[option.Value] = option.Name
+			}
+		}
+		c.index[field.ID] = indexed
+	}
+	c.indexed = fields
+	return c.index
+}
+
+// sameFields reports whether both slices are the same slice, the definitions being replaced as a whole on refresh
+func sameFields(a, b []TicketField) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// coerce converts the value of a custom field to the type of the field, i.e. numbers sent as strings.
+// Values which can't be converted are returned as is.
+func (f customField) coerce(value interface{}) interface{} {
+	if n, ok := value.(float64); ok && (f.fieldType == "integer" || f.fieldType == "lookup") && n == math.Trunc(n) {
+		return int64(n)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch f.fieldType {
+	case "integer", "lookup":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "decimal":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	case "checkbox":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "date":
+		// dates are sent as 2022-05-08, and sometimes as times, i.e. 2022-05-08T00:00:00+00:00, the date is kept at midnight UTC
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, s)
+		}
+		if err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+		}
+	case "tagger":
+		// the tag of the selected option, empty when none is, along with the name of the option when it is known
+		if s == "" {
+			return nil
+		}
+		option := map[string]interface{}{"value": s}
+		if name, ok := f.options[s]; ok {
+			option["name"] = name
+		}
+		return option
+	case "multiselect":
+		return strings.Fields(s)
+	}
+	return value
+}
+
+// formatID formats the id of a custom field, without exponent
+func formatID(id float64) string {
+	return strconv.FormatFloat(id, 'f', -1, 64)
+}
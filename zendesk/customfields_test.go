@@ -0,0 +1,130 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomFieldKeys(t *testing.T) {
+	fields := []TicketField{
+		{ID: 1, Type: "subject", Title: "Subject"},
+		{ID: 360001, Type: "tagger", Title: "Plan", Removable: true},
+		{ID: 360002, Type: "text", Title: "Region", Removable: true},
+		{ID: 360003, Type: "text", Title: "Region", Removable: true},
+		{ID: 360004, Type: "decimal", Title: "Refund", Removable: true},
+		{ID: 360012345678, Type: "text", Removable: true},
+	}
+	keys := CustomFieldKeys(fields, map[float64]string{360004: "refund_amount"})
+	assert.Equal(t, map[float64]string{
+		360001:       "Plan",
+		360002:       "360002", // the titles shared by several fields are replaced by the ids
+		360003:       "360003",
+		360004:       "refund_amount",
+		360012345678: "360012345678",
+	}, keys)
+}
+
+func TestCustomFields_Flatten(t *testing.T) {
+	fields := []TicketField{
+		{ID: 360001, Type: "tagger", Title: "Plan", Removable: true, Options: []FieldOption{{Name: "Gold", Value: "plan_gold"}}},
+		{ID: 360002, Type: "integer", Title: "Seats", Removable: true},
+		{ID: 360003, Type: "decimal", Title: "Refund", Removable: true},
+		{ID: 360004, Type: "date", Title: "Renewal", Removable: true},
+		{ID: 360005, Type: "checkbox", Title: "VIP", Removable: true},
+		{ID: 360006, Type: "multiselect", Title: "Products", Removable: true},
+	}
+	cf := NewCustomFields(func() []TicketField { return fields }, map[float64]string{360002: "seats"})
+
+	object := map[string]interface{}{
+		"id": float64(12),
+		"custom_fields": []interface{}{
+			map[string]interface{}{"id": float64(360001), "value": "plan_gold"},
+			map[string]interface{}{"id": float64(360002), "value": "25"},
+			map[string]interface{}{"id": float64(360003), "value": "12.50"},
+			map[string]interface{}{"id": float64(360004), "value": "2022-05-08T00:00:00+00:00"},
+			map[string]interface{}{"id": float64(360005), "value": true},
+			map[string]interface{}{"id": float64(360006), "value": []interface{}{"chat", "voice"}},
+			map[string]interface{}{"id": float64(360007), "value": "added since the refresh"},
+		},
+	}
+	flattened := cf.Flatten(object)
+	assert.Equal(t, map[string]interface{}{
+		"Plan":     map[string]interface{}{"value": "plan_gold", "name": "Gold"},
+		"seats":    int64(25),
+		"Refund":   12.5,
+		"Renewal":  "2022-05-08T00:00:00Z",
+		"VIP":      true,
+		"Products": []interface{}{"chat", "voice"},
+		"360007":   "added since the refresh",
+	}, flattened["custom_fields"])
+	assert.Equal(t, float64(12), flattened["id"])
+	// the object isn't modified
+	assert.IsType(t, []interface{}{}, object["custom_fields"])
+
+	// the index is built again once the fields are refreshed
+	fields = []TicketField{{ID: 360001, Type: "tagger", Title: "Subscription", Removable: true}}
+	flattened = cf.Flatten(map[string]interface{}{
+		"custom_fields": []interface{}{map[string]interface{}{"id": float64(360001), "value": ""}},
+	})
+	assert.Equal(t, map[string]interface{}{"Subscription": nil}, flattened["custom_fields"])
+
+	// objects without custom fields are returned as is
+	user := map[string]interface{}{"user_fields": map[string]interface{}{"plan": "gold"}}
+	assert.Equal(t, user, cf.Flatten(user))
+}
+
+func TestCustomField_coerce(t *testing.T) {
+	options := map[string]string{"plan_gold": "Gold"}
+	tests := []struct {
+		name      string
+		fieldType string
+		value     interface{}
+		want      interface{}
+	}{
+		{name: "integer string", fieldType: "integer", value: "25", want: int64(25)},
+		{name: "integer number", fieldType: "integer", value: float64(25), want: int64(25)},
+		{name: "integer invalid", fieldType: "integer", value: "many", want: "many"},
+		{name: "lookup", fieldType: "lookup", value: "360012345678", want: int64(360012345678)},
+		{name: "lookup number", fieldType: "lookup", value: float64(360012345678), want: int64(360012345678)},
+		{name: "lookup empty", fieldType: "lookup", value: nil, want: nil},
+		{name: "decimal", fieldType: "decimal", value: "12.50", want: 12.5},
+		{name: "decimal number", fieldType: "decimal", value: 12.5, want: 12.5},
+		{name: "checkbox", fieldType: "checkbox", value: true, want: true},
+		{name: "checkbox string", fieldType: "checkbox", value: "false", want: false},
+		{name: "date", fieldType: "date", value: "2022-05-08", want: "2022-05-08T00:00:00Z"},
+		{name: "date time", fieldType: "date", value: "2022-05-08T00:00:00+02:00", want: "2022-05-08T00:00:00Z"},
+		{name: "date invalid", fieldType: "date", value: "tomorrow", want: "tomorrow"},
+		{name: "date empty", fieldType: "date", value: nil, want: nil},
+		{name: "tagger", fieldType: "tagger", value: "plan_gold", want: map[string]interface{}{"value": "plan_gold", "name": "Gold"}},
+		{name: "tagger unknown option", fieldType: "tagger", value: "plan_silver", want: map[string]interface{}{"value": "plan_silver"}},
+		{name: "tagger empty", fieldType: "tagger", value: "", want: nil},
+		{name: "multiselect", fieldType: "multiselect", value: []interface{}{"chat", "voice"}, want: []interface{}{"chat", "voice"}},
+		{name: "multiselect string", fieldType: "multiselect", value: "chat voice", want: []string{"chat", "voice"}},
+		{name: "text", fieldType: "text", value: "25", want: "25"},
+		{name: "textarea", fieldType: "textarea", value: "line\nline", want: "line\nline"},
+		{name: "regexp", fieldType: "regexp", value: "ABC-12", want: "ABC-12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := customField{key: "field", fieldType: tt.fieldType, options: options}
+			assert.Equal(t, tt.want, field.coerce(tt.value))
+		})
+	}
+}
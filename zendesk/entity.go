@@ -0,0 +1,148 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"sort"
+)
+
+// entities supported by the source connector
+const (
+	EntityTickets             = "tickets"
+	EntitySatisfactionRatings = "satisfaction_ratings"
+	EntityArticles            = "articles"
+	EntityUsers               = "users"
+	EntityOrganizations       = "organizations"
+	// EntitySearch is the entity of the search results, read instead of the other entities in search mode
+	EntitySearch = "search"
+)
+
+// Pagination is the strategy used to iterate over the export endpoint of an entity
+type Pagination int
+
+const (
+	// PaginationCursor iterates using the `after_url` returned by the cursor based incremental exports,
+	// the cursor is never reset, as zendesk keeps returning the `after_url` even after `end_of_stream`
+	PaginationCursor Pagination = iota
+	// PaginationTime iterates using the `next_page` returned by time based exports,
+	// once the last page is read, the export is restarted using the last modified time as `start_time`
+	PaginationTime
+	// PaginationSearch iterates using the `links.next` url returned by the search export, while `meta.has_more` is set.
+	// The results aren't ordered, so the query is run again once the last page is read, for the objects updated after
+	// the last modified time of the previous run.
+	PaginationSearch
+)
+
+// Entity describes how a zendesk entity is exported and converted to records
+type Entity struct {
+	Name           string            // name of the entity, used in the source config
+	Endpoint       string            // export endpoint, relative to the zendesk base url
+	ListField      string            // response field holding the list of entity objects
+	IDField        string            // field used as the record key and position id
+	TimestampField string            // field used as the last modified time in position
+	Pagination     Pagination        // pagination strategy of the export endpoint
+	Metadata       map[string]string // record metadata key to the entity field copied into it
+	Query          string            // search query of the search entity
+	ObjectType     string            // type of the objects searched by the search entity
+}
+
+var (
+	Tickets = Entity{
+		Name:           EntityTickets,
+		Endpoint:       "/api/v2/incremental/tickets/cursor.json",
+		ListField:      "tickets",
+		IDField:        "id",
+		TimestampField: "updated_at",
+		Pagination:     PaginationCursor,
+	}
+	SatisfactionRatings = Entity{
+		Name:           EntitySatisfactionRatings,
+		Endpoint:       "/api/v2/satisfaction_ratings.json",
+		ListField:      "satisfaction_ratings",
+		IDField:        "id",
+		TimestampField: "updated_at",
+		Pagination:     PaginationTime,
+		Metadata:       map[string]string{"ticket_id": "ticket_id"},
+	}
+	Articles = Entity{
+		Name:           EntityArticles,
+		Endpoint:       "/api/v2/help_center/incremental/articles.json",
+		ListField:      "articles",
+		IDField:        "id",
+		TimestampField: "updated_at",
+		Pagination:     PaginationTime,
+		Metadata:       map[string]string{"locale": "locale"},
+	}
+	Users = Entity{
+		Name:           EntityUsers,
+		Endpoint:       "/api/v2/incremental/users/cursor.json",
+		ListField:      "users",
+		IDField:        "id",
+		TimestampField: "updated_at",
+		Pagination:     PaginationCursor,
+	}
+	Organizations = Entity{
+		Name:           EntityOrganizations,
+		Endpoint:       "/api/v2/incremental/organizations.json",
+		ListField:      "organizations",
+		IDField:        "id",
+		TimestampField: "updated_at",
+		Pagination:     PaginationTime,
+	}
+)
+
+// SearchEntity returns the descriptor of the objects of the type matching the search query, i.e. `tags:vip`,
+// exported with the search export api
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/ticket-management/search/#export-search-results
+func SearchEntity(query, objectType string) Entity {
+	return Entity{
+		Name:           EntitySearch,
+		Endpoint:       "/api/v2/search/export.json",
+		ListField:      "results",
+		IDField:        "id",
+		TimestampField: "updated_at",
+		Pagination:     PaginationSearch,
+		Metadata:       map[string]string{"result_type": "result_type"},
+		Query:          query,
+		ObjectType:     objectType,
+	}
+}
+
+// entities holds the descriptors of all the supported entities, keyed by name
+var entities = map[string]Entity{
+	Tickets.Name:             Tickets,
+	SatisfactionRatings.Name: SatisfactionRatings,
+	Articles.Name:            Articles,
+	Users.Name:               Users,
+	Organizations.Name:       Organizations,
+}
+
+// LookupEntity returns the descriptor of the entity with the given name
+func LookupEntity(name string) (Entity, bool) {
+	e, ok := entities[name]
+	return e, ok
+}
+
+// EntityNames returns the sorted names of all the supported entities
+func EntityNames() []string {
+	names := make([]string, 0, len(entities))
+	for name := range entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,36 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupEntity(t *testing.T) {
+	entity, ok := LookupEntity("tickets")
+	assert.True(t, ok)
+	assert.Equal(t, Tickets, entity)
+
+	_, ok = LookupEntity("calls")
+	assert.False(t, ok)
+}
+
+func TestEntityNames(t *testing.T) {
+	assert.Equal(t, []string{"articles", "organizations", "satisfaction_ratings", "tickets", "users"}, EntityNames())
+}
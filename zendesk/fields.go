@@ -0,0 +1,76 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ticketFieldsEndpoint lists the system and custom ticket fields of the account
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/tickets/ticket_fields/#list-ticket-fields
+const ticketFieldsEndpoint = "/api/v2/ticket_fields.json"
+
+// TicketField is the definition of a ticket field, either a system field, i.e. subject, or a custom field
+type TicketField struct {
+	ID        float64       `json:"id"`
+	Type      string        `json:"type"`  // i.e. text, tagger, date, decimal for custom fields, subject, status for system fields
+	Title     string        `json:"title"` // title displayed to the agents
+	Active    bool          `json:"active"`
+	Removable bool          `json:"removable"` // false for the system fields
+	Options   []FieldOption `json:"custom_field_options,omitempty"`
+}
+
+// FieldOption is an option of a tagger or multiselect custom field
+type FieldOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"` // tag set on the ticket when the option is selected
+}
+
+// Custom reports whether the field is a custom field, stored in the `custom_fields` of the tickets
+func (f TicketField) Custom() bool {
+	return f.Removable
+}
+
+type ticketFieldsResponse struct {
+	TicketFields []TicketField `json:"ticket_fields"`
+	NextPage     *string       `json:"next_page"`
+}
+
+// FetchTicketFields returns the definitions of all the ticket fields of the account, reading every page
+func FetchTicketFields(ctx context.Context, client *Client) ([]TicketField, error) {
+	var fields []TicketField
+	url := ticketFieldsEndpoint
+	for url != "" {
+		body, err := client.Get(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch the ticket fields: %w", err)
+		}
+		var res ticketFieldsResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			return nil, fmt.Errorf("error unmarshaling the ticket fields: %w", err)
+		}
+		fields = append(fields, res.TicketFields...)
+
+		url = ""
+		if res.NextPage != nil && len(res.TicketFields) > 0 {
+			url = *res.NextPage
+		}
+	}
+	return fields, nil
+}
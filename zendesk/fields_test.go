@@ -0,0 +1,54 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchTicketFields(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, ticketFieldsEndpoint, r.URL.Path)
+		switch r.URL.Query().Get("page") {
+		case "":
+			_, _ = fmt.Fprintf(w, `{"ticket_fields":[{"id":1,"type":"subject","title":"Subject","active":true,"removable":false}],`+
+				`"next_page":"%s%s?page=2"}`, server.URL, ticketFieldsEndpoint)
+		case "2":
+			_, _ = w.Write([]byte(`{"ticket_fields":[{"id":360001,"type":"tagger","title":"Plan","active":true,"removable":true,` +
+				`"custom_field_options":[{"name":"Gold","value":"plan_gold"}]}],"next_page":null}`))
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	fields, err := FetchTicketFields(context.Background(), NewClient(server.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, []TicketField{
+		{ID: 1, Type: "subject", Title: "Subject", Active: true},
+		{ID: 360001, Type: "tagger", Title: "Plan", Active: true, Removable: true, Options: []FieldOption{{Name: "Gold", Value: "plan_gold"}}},
+	}, fields)
+	assert.False(t, fields[0].Custom())
+	assert.True(t, fields[1].Custom())
+}
@@ -17,80 +17,51 @@ limitations under the License.
 package zendesk
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strconv"
+	"net/url"
 	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 )
 
-const defaultRetryAfter = 93
-
 type CreateManyRequest struct {
 	Tickets []map[string]interface{} `json:"tickets"`
 }
 
 type BulkImporter struct {
-	userName   string       // userName to login as admin to zendesk
-	apiToken   string       // token to authenticate the user
-	client     *http.Client // http client to connect zendesk
-	maxRetries uint64       // max API retries in case of 429, before returning error
-	baseURL    string       // zendesk api url
-	retryCount uint64       // number of retry count made for current data
+	client     *Client // http client to connect zendesk
+	maxRetries uint64  // max API retries in case of 429, before returning error
+	retryCount uint64  // number of retry count made for current data
+	verify     bool    // the tickets of the next batch may be imported already, by a write which failed before a restart
 }
 
 // NewBulkImporter initialize bulk importer to write bulk tickets to zendesk
-func NewBulkImporter(userName, apiToken, domain string, maxRetries uint64) *BulkImporter {
+func NewBulkImporter(client *Client, maxRetries uint64) *BulkImporter {
 	return &BulkImporter{
-		client:     newHTTPClient(),
-		userName:   userName,
-		apiToken:   apiToken,
-		baseURL:    fmt.Sprintf("https://%s.zendesk.com", domain),
+		client:     client,
 		maxRetries: maxRetries,
+		verify:     true,
 	}
 }
 
-// Write buffer data to zendesk
+// Write buffer data to zendesk. Only 429 responses are retried, other failures are returned,
+// as zendesk may have imported the tickets already, e.g. if the request timed out.
+// The records are then delivered again after a restart, so the tickets of the first batches, which have an external id,
+// are skipped if zendesk already has a ticket with the same external id.
 func (b *BulkImporter) Write(ctx context.Context, records []sdk.Record) error {
-	bufferedTicket, err := parseRecords(records)
+	tickets, err := parseRecords(records)
 	if err != nil {
 		return fmt.Errorf("unable to parse the records %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		fmt.Sprintf("%s/api/v2/imports/tickets/create_many", b.baseURL),
-		bytes.NewBuffer(bufferedTicket),
-	)
-
-	if err != nil {
-		return fmt.Errorf("unable to send to zendesk server %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
-	req.Header.Add("Authorization", "Basic "+basicAuth(b.userName, b.apiToken))
-
-	resp, err := b.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("got error response when writing records to zendesk %w", err)
-	}
-
-	defer resp.Body.Close()
-	// Validation for httpStatusCode 429 - Too many Requests, Retry value after `93s`
-	if resp.StatusCode == http.StatusTooManyRequests {
-		// NOTE: https://developer.zendesk.com/documentation/ticketing/using-the-zendesk-api/best-practices-for-avoiding-rate-limiting/#catching-errors-caused-by-rate-limiting
-		retryValue, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64)
-		if err != nil {
-			retryValue = defaultRetryAfter
-		}
+	err = b.importTickets(ctx, tickets)
 
-		sdk.Logger(ctx).Trace().Int64("Retry-After", retryValue).Msg("rate limit exceeded, will retry after `Retry-After` duration")
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		sdk.Logger(ctx).Trace().Dur("Retry-After", rateLimitErr.RetryAfter).Msg("rate limit exceeded, will retry after `Retry-After` duration")
 
 		if b.retryCount >= b.maxRetries {
 			return fmt.Errorf("rate-limit exceeded, total retries: %d", b.retryCount)
@@ -101,7 +72,7 @@ func (b *BulkImporter) Write(ctx context.Context, records []sdk.Record) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(time.Duration(retryValue) * time.Second):
+		case <-time.After(rateLimitErr.RetryAfter):
 			return b.Write(ctx, records)
 		}
 	}
@@ -109,34 +80,79 @@ func (b *BulkImporter) Write(ctx context.Context, records []sdk.Record) error {
 	// reset the retry count, in case of non 429 response.
 	b.retryCount = 0
 
-	if resp.StatusCode != http.StatusOK {
-		// no use checking the error, if it errors, we will just have empty body message in error
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("non 200 status code(%d) received(%v)", resp.StatusCode, string(bodyBytes))
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr
+	}
+	if err != nil {
+		return fmt.Errorf("got error response when writing records to zendesk %w", err)
 	}
-
 	return nil
 }
 
-// parseRecords unmarshal the payload data from records to map[string]interface{}
-// and returns a marshalled CreateManyRequest, to be used to write multiple tickets to zendesk
-func parseRecords(records []sdk.Record) ([]byte, error) {
-	output := CreateManyRequest{
-		Tickets: make([]map[string]interface{}, 0, len(records)),
+// importTickets posts the tickets to the bulk import, once the tickets already imported are skipped, if verified
+func (b *BulkImporter) importTickets(ctx context.Context, tickets []map[string]interface{}) error {
+	if b.verify {
+		var err error
+		tickets, err = b.skipImported(ctx, tickets)
+		if err != nil {
+			return err
+		}
+		if len(tickets) == 0 {
+			return nil
+		}
+	}
+
+	body, err := json.Marshal(CreateManyRequest{Tickets: tickets})
+	if err != nil {
+		return fmt.Errorf("error marshaling the create tickets request: %w", err)
+	}
+	_, err = b.client.Post(ctx, "/api/v2/imports/tickets/create_many", body)
+	return err
+}
+
+// skipImported drops the tickets whose external id is already used by a zendesk ticket. The batches are verified
+// till one has none of its tickets imported already, as only the batches delivered again can be.
+func (b *BulkImporter) skipImported(ctx context.Context, tickets []map[string]interface{}) ([]map[string]interface{}, error) {
+	remaining := make([]map[string]interface{}, 0, len(tickets))
+	for _, ticket := range tickets {
+		externalID, _ := ticket["external_id"].(string)
+		if externalID == "" {
+			remaining = append(remaining, ticket)
+			continue
+		}
+
+		body, err := b.client.Get(ctx, "/api/v2/tickets.json?external_id="+url.QueryEscape(externalID))
+		if err != nil {
+			return nil, fmt.Errorf("unable to look up the tickets with external id %q: %w", externalID, err)
+		}
+		var res struct {
+			Tickets []json.RawMessage `json:"tickets"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return nil, fmt.Errorf("error unmarshaling the tickets with external id %q: %w", externalID, err)
+		}
+		if len(res.Tickets) > 0 {
+			sdk.Logger(ctx).Info().Str("external_id", externalID).Msg("ticket already imported, skipping it")
+			continue
+		}
+		remaining = append(remaining, ticket)
 	}
+	b.verify = len(remaining) < len(tickets)
+	return remaining, nil
+}
 
+// parseRecords unmarshal the payload data from records to map[string]interface{},
+// the tickets of the CreateManyRequest used to write multiple tickets to zendesk
+func parseRecords(records []sdk.Record) ([]map[string]interface{}, error) {
+	tickets := make([]map[string]interface{}, 0, len(records))
 	for _, record := range records {
 		var ticket map[string]interface{}
 		err := json.Unmarshal(record.Payload.Bytes(), &ticket)
 		if err != nil {
 			return nil, fmt.Errorf("error unmarshaling the payload into map: %w", err)
 		}
-		output.Tickets = append(output.Tickets, ticket)
-	}
-
-	m, err := json.Marshal(output)
-	if err != nil {
-		return nil, fmt.Errorf("error marshaling the create tickets request: %w", err)
+		tickets = append(tickets, ticket)
 	}
-	return m, nil
+	return tickets, nil
 }
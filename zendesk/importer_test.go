@@ -18,12 +18,14 @@ package zendesk
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/conduitio/conduit-connector-zendesk/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -62,10 +64,13 @@ func TestNewWriter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res := NewBulkImporter(tt.userName, tt.apiToken, tt.domain, tt.maxRetries)
+			client, err := NewAccountClient(config.Config{Domain: tt.domain, UserName: tt.userName, APIToken: tt.apiToken})
+			assert.NoError(t, err)
+			res := NewBulkImporter(client, tt.maxRetries)
 			assert.NotNil(t, res)
-			assert.Equal(t, tt.userName, res.userName)
-			assert.Equal(t, tt.apiToken, res.apiToken)
+			assert.NotNil(t, res.client)
+			assert.Equal(t, fmt.Sprintf("https://%s.zendesk.com", tt.domain), res.client.baseURL)
+			assert.Equal(t, tt.maxRetries, res.maxRetries)
 		})
 	}
 }
@@ -83,10 +88,7 @@ func TestWrite_RawDataPayload(t *testing.T) {
 	}
 	testServer := httptest.NewServer(th)
 	writer := &BulkImporter{
-		baseURL:  testServer.URL,
-		client:   &http.Client{},
-		userName: th.username,
-		apiToken: th.apiToken,
+		client: newTestClient(testServer.URL, th.username, th.apiToken),
 	}
 
 	var inputRecords []sdk.Record
@@ -113,10 +115,7 @@ func TestWrite_StructuredDataPayload(t *testing.T) {
 	}
 	testServer := httptest.NewServer(th)
 	writer := &BulkImporter{
-		baseURL:  testServer.URL,
-		client:   &http.Client{},
-		userName: th.username,
-		apiToken: th.apiToken,
+		client: newTestClient(testServer.URL, th.username, th.apiToken),
 	}
 
 	var inputRecords []sdk.Record
@@ -151,10 +150,7 @@ func TestWrite_429(t *testing.T) {
 	testServer := httptest.NewServer(th)
 
 	writer := &BulkImporter{
-		baseURL:    testServer.URL,
-		client:     &http.Client{},
-		userName:   th.username,
-		apiToken:   th.apiToken,
+		client:     newTestClient(testServer.URL, th.username, th.apiToken),
 		retryCount: 1,
 	}
 	var inputRecords []sdk.Record
@@ -189,10 +185,7 @@ func TestWrite_500(t *testing.T) {
 	testServer := httptest.NewServer(th)
 
 	writer := &BulkImporter{
-		baseURL:  testServer.URL,
-		client:   newHTTPClient(),
-		userName: th.username,
-		apiToken: th.apiToken,
+		client: newTestClient(testServer.URL, th.username, th.apiToken),
 	}
 	var inputRecords []sdk.Record
 	inputBytes := []byte(`{
@@ -211,3 +204,37 @@ func TestWrite_500(t *testing.T) {
 	err := writer.Write(ctx, inputRecords)
 	assert.EqualError(t, err, "non 200 status code(500) received(some_dummy_error)")
 }
+
+func TestWrite_SkipsImported(t *testing.T) {
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/tickets.json":
+			tickets := "[]"
+			if r.URL.Query().Get("external_id") == "1" {
+				tickets = `[{"id":1,"external_id":"1"}]`
+			}
+			_, _ = w.Write([]byte(`{"tickets":` + tickets + `}`))
+		case "/api/v2/imports/tickets/create_many":
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			posted = append(posted, string(body))
+			_, _ = w.Write([]byte(`{"job_status":{"status":"queued"}}`))
+		}
+	}))
+	defer server.Close()
+
+	writer := NewBulkImporter(NewClient(server.URL), 3)
+	records := []sdk.Record{
+		{Payload: sdk.RawData(`{"external_id":"1","subject":"imported"}`)},
+		{Payload: sdk.RawData(`{"external_id":"2","subject":"missing"}`)},
+		{Payload: sdk.RawData(`{"subject":"without external id"}`)},
+	}
+	assert.NoError(t, writer.Write(context.Background(), records))
+	assert.Equal(t, []string{`{"tickets":[{"external_id":"2","subject":"missing"},{"subject":"without external id"}]}`}, posted)
+	// a ticket was imported already, so the next batch is verified too
+	assert.True(t, writer.verify)
+
+	assert.NoError(t, writer.Write(context.Background(), records[1:]))
+	assert.False(t, writer.verify)
+}
@@ -0,0 +1,292 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/config"
+)
+
+const (
+	oauthTokenPath = "/oauth/tokens"
+
+	// tokenExpiryMargin is the time before the expiry at which the access token is renewed,
+	// so the requests in flight still carry a valid token
+	tokenExpiryMargin = time.Minute
+
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeRefreshToken      = "refresh_token"
+)
+
+// TokenSource provides the OAuth access token used to authenticate the requests
+type TokenSource interface {
+	// Token returns a valid access token
+	Token(ctx context.Context) (string, error)
+	// Invalidate discards the token rejected by zendesk, so the next call to Token renews it
+	Invalidate(token string)
+}
+
+// StaticToken is an access token which is never renewed by the connector.
+// It may reference a secret, resolved on every call so a rotated secret is picked up.
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return config.ResolveSecret(string(t))
+}
+
+func (t StaticToken) Invalidate(string) {}
+
+// OAuthTokenSource requests access tokens from zendesk using the client credentials, or the refresh token when one is set,
+// and renews them before they expire
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/oauth/oauth_tokens/#create-token
+type OAuthTokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	refreshToken string // reference of the file holding the refresh token, the rotated refresh token is written to it
+	scope        string
+	client       *http.Client
+
+	mux      sync.Mutex
+	token    string    // current access token, empty till the first renewal
+	expiry   time.Time // expiry of the current access token, zero if it doesn't expire
+	renewing *renewal  // renewal in progress, nil if none is
+	now      func() time.Time
+}
+
+// renewal is a renewal of the access token, awaited by the concurrent callers
+type renewal struct {
+	done chan struct{} // closed once the renewal completes
+	err  error
+}
+
+type tokenRequest struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// NewOAuthTokenSource returns the token source requesting tokens from the zendesk token url
+func NewOAuthTokenSource(tokenURL string, cfg config.OAuthConfig) *OAuthTokenSource {
+	return &OAuthTokenSource{
+		tokenURL:     tokenURL,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		scope:        cfg.Scope,
+		refreshToken: cfg.RefreshToken,
+		client:       &http.Client{Timeout: defaultTimeout},
+		now:          time.Now,
+	}
+}
+
+// Token returns the current access token, renewing it if it is missing or about to expire.
+// The token is requested without holding the lock: while it is, the callers keep the current token till it expires,
+// and wait for the same renewal otherwise, instead of requesting a token each.
+func (s *OAuthTokenSource) Token(ctx context.Context) (string, error) {
+	for {
+		s.mux.Lock()
+		r := s.renewing
+		if s.token != "" && (s.expiry.IsZero() || s.now().Add(tokenExpiryMargin).Before(s.expiry) ||
+			(r != nil && s.now().Before(s.expiry))) {
+			token := s.token
+			s.mux.Unlock()
+			return token, nil
+		}
+		if r == nil {
+			r = &renewal{done: make(chan struct{})}
+			s.renewing = r
+			s.mux.Unlock()
+			s.renew(ctx, r)
+		} else {
+			s.mux.Unlock()
+		}
+
+		select {
+		case <-r.done:
+			if r.err != nil {
+				return "", r.err
+			}
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Invalidate discards the token, unless it was already renewed by another request
+func (s *OAuthTokenSource) Invalidate(token string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// renew requests a new access token and completes the renewal once the token is set
+func (s *OAuthTokenSource) renew(ctx context.Context, r *renewal) {
+	resp, err := s.requestToken(ctx)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err == nil {
+		s.token = resp.AccessToken
+		s.expiry = time.Time{}
+		if resp.ExpiresIn > 0 {
+			s.expiry = s.now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+		}
+	}
+	r.err = err
+	s.renewing = nil
+	close(r.done)
+}
+
+// requestToken requests a new access token from zendesk, the rotated refresh token, if any, is written to its file
+// before the access token is returned, so the connector doesn't use a revoked refresh token after a restart
+func (s *OAuthTokenSource) requestToken(ctx context.Context) (tokenResponse, error) {
+	clientSecret, err := config.ResolveSecret(s.clientSecret)
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("could not get the oauth client secret: %w", err)
+	}
+	tokenReq := tokenRequest{
+		GrantType:    grantTypeClientCredentials,
+		ClientID:     s.clientID,
+		ClientSecret: clientSecret,
+		Scope:        s.scope,
+	}
+	if s.refreshToken != "" {
+		refreshToken, err := config.ResolveSecret(s.refreshToken)
+		if err != nil {
+			return tokenResponse{}, fmt.Errorf("could not get the oauth refresh token: %w", err)
+		}
+		tokenReq.GrantType = grantTypeRefreshToken
+		tokenReq.RefreshToken = refreshToken
+	}
+	body, err := json.Marshal(tokenReq)
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("could not marshal the token request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, bytes.NewReader(body))
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("could not create the token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("could not request the oauth token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return tokenResponse{}, &StatusError{StatusCode: resp.StatusCode, Body: respBody}
+	}
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("error reading the token response: %w", err)
+	}
+
+	var tokenResp tokenResponse
+	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
+		return tokenResponse{}, fmt.Errorf("could not unmarshal the token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return tokenResponse{}, fmt.Errorf("no access token received from zendesk")
+	}
+
+	if s.refreshToken != "" && tokenResp.RefreshToken != "" && tokenResp.RefreshToken != tokenReq.RefreshToken {
+		if err := config.WriteSecret(s.refreshToken, tokenResp.RefreshToken); err != nil {
+			return tokenResponse{}, fmt.Errorf("could not persist the rotated oauth refresh token: %w", err)
+		}
+	}
+	return tokenResp, nil
+}
+
+// BearerAuth authenticates the requests using the OAuth access token of the token source.
+// A request rejected with 401 is retried once with a renewed token, so the requests in flight
+// while the token is rotated don't fail.
+func BearerAuth(tokens TokenSource) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			token, err := tokens.Token(req.Context())
+			if err != nil {
+				return nil, fmt.Errorf("could not get the oauth access token: %w", err)
+			}
+			resp, err := next.RoundTrip(withBearer(req, token))
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			tokens.Invalidate(token)
+			renewed, err := tokens.Token(req.Context())
+			if err != nil || renewed == token {
+				// nothing to retry with, return the rejected response
+				return resp, nil
+			}
+			retry := withBearer(req, renewed)
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return resp, nil
+				}
+				body, err := req.GetBody()
+				if err != nil {
+					return resp, nil
+				}
+				retry.Body = body
+			}
+			resp.Body.Close()
+			return next.RoundTrip(retry)
+		})
+	}
+}
+
+func withBearer(req *http.Request, token string) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+// authMiddleware returns the authentication middleware for the config auth type,
+// along with the credential identifying the account for the shared rate limiter.
+// The token requests are made with the http client.
+func authMiddleware(baseURL string, cfg config.Config, client *http.Client) (Middleware, string) {
+	switch {
+	case cfg.AuthType != config.AuthTypeOAuth:
+		return BasicAuth(cfg.UserName, cfg.APIToken), cfg.UserName + "\x00" + cfg.APIToken
+	case cfg.OAuth.AccessToken != "":
+		return BearerAuth(StaticToken(cfg.OAuth.AccessToken)), cfg.OAuth.AccessToken
+	default:
+		tokens := NewOAuthTokenSource(baseURL+oauthTokenPath, cfg.OAuth)
+		tokens.client = client
+		return BearerAuth(tokens), cfg.OAuth.ClientID
+	}
+}
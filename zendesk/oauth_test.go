@@ -0,0 +1,281 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenServer issues numbered access tokens, expiring after expiresIn seconds
+type tokenServer struct {
+	t         *testing.T
+	issued    int32
+	expiresIn int64
+	requests  []tokenRequest
+}
+
+func (ts *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(ts.t, oauthTokenPath, r.URL.Path)
+	var req tokenRequest
+	assert.NoError(ts.t, json.NewDecoder(r.Body).Decode(&req))
+	ts.requests = append(ts.requests, req)
+
+	n := atomic.AddInt32(&ts.issued, 1)
+	_ = json.NewEncoder(w).Encode(tokenResponse{
+		AccessToken:  fmt.Sprintf("token-%d", n),
+		RefreshToken: fmt.Sprintf("refresh-%d", n),
+		ExpiresIn:    ts.expiresIn,
+	})
+}
+
+func TestOAuthTokenSource_ClientCredentials(t *testing.T) {
+	ts := &tokenServer{t: t, expiresIn: 3600}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	now := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	source := NewOAuthTokenSource(server.URL+oauthTokenPath, config.OAuthConfig{
+		ClientID:     "dummy_client",
+		ClientSecret: "dummy_secret",
+		Scope:        "read",
+	})
+	source.now = func() time.Time { return now }
+
+	token, err := source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// the token is reused till it is about to expire
+	now = now.Add(58 * time.Minute)
+	token, err = source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// renewed before the expiry, with the client credentials again
+	now = now.Add(90 * time.Second)
+	token, err = source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+
+	assert.Equal(t, []tokenRequest{
+		{GrantType: grantTypeClientCredentials, ClientID: "dummy_client", ClientSecret: "dummy_secret", Scope: "read"},
+		{GrantType: grantTypeClientCredentials, ClientID: "dummy_client", ClientSecret: "dummy_secret", Scope: "read"},
+	}, ts.requests)
+}
+
+func TestOAuthTokenSource_RefreshToken(t *testing.T) {
+	ts := &tokenServer{t: t, expiresIn: 3600}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "refresh-token")
+	assert.NoError(t, os.WriteFile(path, []byte("refresh-0\n"), 0o600))
+	now := time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC)
+	source := NewOAuthTokenSource(server.URL+oauthTokenPath, config.OAuthConfig{
+		ClientID:     "dummy_client",
+		ClientSecret: "dummy_secret",
+		RefreshToken: config.SecretFilePrefix + path + config.SecretSuffix,
+		Scope:        "read",
+	})
+	source.now = func() time.Time { return now }
+
+	token, err := source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	// the rotated refresh token is written to the file, so it is used after a restart
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh-1", string(content))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	now = now.Add(time.Hour)
+	token, err = source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+
+	assert.Equal(t, []tokenRequest{
+		{GrantType: grantTypeRefreshToken, ClientID: "dummy_client", ClientSecret: "dummy_secret", RefreshToken: "refresh-0", Scope: "read"},
+		{GrantType: grantTypeRefreshToken, ClientID: "dummy_client", ClientSecret: "dummy_secret", RefreshToken: "refresh-1", Scope: "read"},
+	}, ts.requests)
+}
+
+func TestOAuthTokenSource_RefreshTokenNotWritten(t *testing.T) {
+	ts := &tokenServer{t: t, expiresIn: 3600}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	t.Setenv("ZENDESK_TEST_REFRESH_TOKEN", "refresh-0")
+	source := NewOAuthTokenSource(server.URL+oauthTokenPath, config.OAuthConfig{
+		ClientID:     "dummy_client",
+		ClientSecret: "dummy_secret",
+		RefreshToken: config.SecretEnvPrefix + "ZENDESK_TEST_REFRESH_TOKEN" + config.SecretSuffix,
+	})
+	_, err := source.Token(context.Background())
+	assert.ErrorContains(t, err, "could not persist the rotated oauth refresh token")
+}
+
+func TestOAuthTokenSource_ConcurrentRenewal(t *testing.T) {
+	ts := &tokenServer{t: t, expiresIn: 3600}
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		ts.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	source := NewOAuthTokenSource(server.URL+oauthTokenPath, config.OAuthConfig{ClientID: "dummy_client", ClientSecret: "dummy_secret"})
+	tokens := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			token, err := source.Token(context.Background())
+			assert.NoError(t, err)
+			tokens <- token
+		}()
+	}
+
+	// the lock isn't held while the token is requested
+	invalidated := make(chan struct{})
+	go func() {
+		source.Invalidate("token-0")
+		close(invalidated)
+	}()
+	select {
+	case <-invalidated:
+	case <-time.After(time.Second):
+		t.Fatal("Invalidate waited for the token request")
+	}
+
+	close(release)
+	assert.Equal(t, "token-1", <-tokens)
+	assert.Equal(t, "token-1", <-tokens)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ts.issued))
+}
+
+func TestOAuthTokenSource_TokenDuringRenewal(t *testing.T) {
+	ts := &tokenServer{t: t, expiresIn: 3600}
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&ts.issued) > 0 {
+			<-release
+		}
+		ts.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	var now atomic.Value
+	now.Store(time.Date(2022, 5, 8, 5, 49, 55, 0, time.UTC))
+	source := NewOAuthTokenSource(server.URL+oauthTokenPath, config.OAuthConfig{ClientID: "dummy_client", ClientSecret: "dummy_secret"})
+	source.now = func() time.Time { return now.Load().(time.Time) }
+	token, err := source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// the token is about to expire, the renewal is stuck
+	now.Store(now.Load().(time.Time).Add(59*time.Minute + 30*time.Second))
+	renewed := make(chan string)
+	go func() {
+		token, err := source.Token(context.Background())
+		assert.NoError(t, err)
+		renewed <- token
+	}()
+	assert.Eventually(t, func() bool {
+		source.mux.Lock()
+		defer source.mux.Unlock()
+		return source.renewing != nil
+	}, time.Second, time.Millisecond)
+
+	// the other requests keep using the current token meanwhile
+	token, err = source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	close(release)
+	assert.Equal(t, "token-2", <-renewed)
+}
+
+func TestOAuthTokenSource_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer server.Close()
+
+	source := NewOAuthTokenSource(server.URL+oauthTokenPath, config.OAuthConfig{ClientID: "dummy_client", ClientSecret: "wrong_secret"})
+	_, err := source.Token(context.Background())
+	assert.EqualError(t, err, `non 200 status code(401) received({"error":"invalid_client"})`)
+}
+
+func TestBearerAuth_RetryOnRotatedToken(t *testing.T) {
+	ts := &tokenServer{t: t, expiresIn: 3600}
+	var apiCalls int32
+	mux := http.NewServeMux()
+	mux.Handle(oauthTokenPath, ts)
+	mux.HandleFunc("/api/v2/imports/tickets/create_many", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&apiCalls, 1)
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"tickets":[]}`, string(body))
+		// the first token was revoked while the request was in flight
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	source := NewOAuthTokenSource(server.URL+oauthTokenPath, config.OAuthConfig{ClientID: "dummy_client", ClientSecret: "dummy_secret"})
+	client := NewClient(server.URL, BearerAuth(source))
+
+	_, err := client.Post(context.Background(), "/api/v2/imports/tickets/create_many", []byte(`{"tickets":[]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&apiCalls))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&ts.issued))
+}
+
+func TestBearerAuth_StaticToken(t *testing.T) {
+	var apiCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&apiCalls, 1)
+		assert.Equal(t, "Bearer dummy_access_token", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	t.Setenv("ZENDESK_ACCESS_TOKEN", "dummy_access_token")
+	client := NewClient(server.URL, BearerAuth(StaticToken("${env:ZENDESK_ACCESS_TOKEN}")))
+	_, err := client.Get(context.Background(), "/api/v2/incremental/tickets/cursor.json")
+	assert.EqualError(t, err, "non 200 status code(401) received()")
+	// a static token can't be renewed, so the request isn't retried
+	assert.Equal(t, int32(1), atomic.LoadInt32(&apiCalls))
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitWindow is the window assumed for the rate limit, when zendesk doesn't send the `ratelimit-reset` header
+const defaultRateLimitWindow = time.Minute
+
+// pacingThreshold is the fraction of the rate limit below which the remaining requests are paced,
+// the requests are sent right away while more requests remain in the window
+const pacingThreshold = 0.2
+
+// RateLimiter paces the requests made to zendesk, using the rate limit headers received in the responses,
+// so the requests are spread over the rate limit window ahead of its exhaustion, instead of exhausting the limit and waiting for a 429
+// NOTE: https://developer.zendesk.com/api-reference/introduction/rate-limits/
+type RateLimiter struct {
+	mux          sync.Mutex
+	limit        int       // requests allowed in the rate limit window, from `X-Rate-Limit`
+	remaining    int       // requests remaining in the current window, from `X-Rate-Limit-Remaining`
+	reset        time.Time // time at which the current window resets, from `ratelimit-reset`
+	blockedUntil time.Time // no requests are allowed till then, set after a 429 response
+	next         time.Time // earliest time at which the next request can be sent
+	now          func() time.Time
+}
+
+// rateLimiters holds the rate limiter shared by the clients built for the same subdomain and credential
+var rateLimiters = struct {
+	sync.Mutex
+	m map[string]*RateLimiter
+}{m: make(map[string]*RateLimiter)}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{now: time.Now}
+}
+
+// SharedRateLimiter returns the rate limiter shared by every client built for the same zendesk url and credential,
+// as zendesk enforces the rate limit per account, irrespective of the number of connectors using it
+func SharedRateLimiter(baseURL, credential string) *RateLimiter {
+	sum := sha256.Sum256([]byte(baseURL + "\x00" + credential))
+	key := hex.EncodeToString(sum[:])
+
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+	limiter, ok := rateLimiters.m[key]
+	if !ok {
+		limiter = NewRateLimiter()
+		rateLimiters.m[key] = limiter
+	}
+	return limiter
+}
+
+// Wait blocks till the next request can be sent without exhausting the rate limit, or the context is done
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	r.mux.Lock()
+	now := r.now()
+	at := r.next
+	if r.blockedUntil.After(at) {
+		at = r.blockedUntil
+	}
+	if at.Before(now) {
+		at = now
+	}
+	// reserve the slot, so the concurrent requests are spread as well
+	r.next = at.Add(r.interval(at))
+	if r.remaining > 0 {
+		r.remaining-- // corrected by the headers of the response
+	}
+	r.mux.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// interval returns the delay to keep between requests, to spread the remaining requests till the window resets,
+// once they fall below the pacing threshold
+func (r *RateLimiter) interval(at time.Time) time.Duration {
+	if r.limit == 0 || !r.reset.After(at) || float64(r.remaining) >= pacingThreshold*float64(r.limit) {
+		return 0
+	}
+	untilReset := r.reset.Sub(at)
+	if r.remaining <= 0 {
+		return untilReset
+	}
+	return untilReset / time.Duration(r.remaining)
+}
+
+// Update records the rate limit state received in the response headers
+func (r *RateLimiter) Update(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit"))
+	if err != nil {
+		return // rate limit headers are not sent by all the endpoints
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	window := defaultRateLimitWindow
+	if reset, err := strconv.Atoi(resp.Header.Get("ratelimit-reset")); err == nil {
+		window = time.Duration(reset) * time.Second
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.limit = limit
+	r.remaining = remaining
+	r.reset = r.now().Add(window)
+	if r.remaining <= 0 {
+		r.next = r.reset
+	}
+}
+
+// Block stops all the requests till the duration passes, used when zendesk responds with 429
+func (r *RateLimiter) Block(d time.Duration) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	until := r.now().Add(d)
+	if until.After(r.blockedUntil) {
+		r.blockedUntil = until
+	}
+}
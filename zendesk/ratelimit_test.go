@@ -0,0 +1,90 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedRateLimiter(t *testing.T) {
+	limiter := SharedRateLimiter("https://testlab.zendesk.com", "user:token")
+	assert.Same(t, limiter, SharedRateLimiter("https://testlab.zendesk.com", "user:token"))
+	assert.NotSame(t, limiter, SharedRateLimiter("https://testlab.zendesk.com", "user:other_token"))
+	assert.NotSame(t, limiter, SharedRateLimiter("https://otherlab.zendesk.com", "user:token"))
+}
+
+func TestRateLimiter_Update(t *testing.T) {
+	now := time.Date(2022, 5, 8, 0, 0, 0, 0, time.UTC)
+	limiter := &RateLimiter{now: func() time.Time { return now }}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("X-Rate-Limit", "700")
+	resp.Header.Set("X-Rate-Limit-Remaining", "10")
+	resp.Header.Set("ratelimit-reset", "20")
+	limiter.Update(resp)
+
+	assert.Equal(t, 700, limiter.limit)
+	assert.Equal(t, 10, limiter.remaining)
+	assert.Equal(t, now.Add(20*time.Second), limiter.reset)
+	// remaining requests are spread over the time left in the window, once below the pacing threshold
+	assert.Equal(t, 2*time.Second, limiter.interval(now))
+
+	// requests aren't paced while the quota is far from exhausted
+	resp.Header.Set("X-Rate-Limit-Remaining", "600")
+	limiter.Update(resp)
+	assert.Equal(t, time.Duration(0), limiter.interval(now))
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.Equal(t, now, limiter.next)
+}
+
+func TestRateLimiter_UpdateWithoutHeaders(t *testing.T) {
+	limiter := NewRateLimiter()
+	limiter.Update(&http.Response{Header: http.Header{}})
+	assert.Equal(t, 0, limiter.limit)
+	assert.Equal(t, time.Duration(0), limiter.interval(time.Now()))
+}
+
+func TestRateLimiter_Exhausted(t *testing.T) {
+	now := time.Date(2022, 5, 8, 0, 0, 0, 0, time.UTC)
+	limiter := &RateLimiter{now: func() time.Time { return now }}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("X-Rate-Limit", "700")
+	resp.Header.Set("X-Rate-Limit-Remaining", "0")
+	resp.Header.Set("ratelimit-reset", "30")
+	limiter.Update(resp)
+	assert.Equal(t, now.Add(30*time.Second), limiter.next)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestRateLimiter_Block(t *testing.T) {
+	limiter := NewRateLimiter()
+	assert.NoError(t, limiter.Wait(context.Background()))
+
+	limiter.Block(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// SearchCursor exports the objects matching the search query of the entity. The search results aren't ordered by
+// update time, so each run of the query reads every object updated after the previous run.
+type SearchCursor struct {
+	export
+	afterURL         string    // url of the next page of the current run
+	lastModifiedTime time.Time // objects updated after the last modified time are searched by the next run
+	runLastModified  time.Time // latest update time of the results read by the current run
+	resumeID         *float64  // id of the last result read from the page the run resumes at, nil once the page is read
+	moved            bool      // the last fetch completed the run without results, moving the cursor past the last record
+}
+
+// NewSearchCursor returns the cursor searching the entity objects updated after the start time, using the zendesk client
+func NewSearchCursor(client *Client, entity Entity, startTime time.Time) *SearchCursor {
+	return &SearchCursor{
+		export:           export{client: client, entity: entity},
+		lastModifiedTime: startTime,
+	}
+}
+
+// Resume resumes the run interrupted at the position, if it was, after the object of the position
+func (c *SearchCursor) Resume(pos position.EntityPosition) {
+	if pos.Search == nil {
+		return
+	}
+	id := pos.ID
+	c.afterURL = pos.Search.Page
+	c.runLastModified = pos.Search.RunLastModified
+	c.resumeID = &id
+}
+
+// Position returns the position the cursor moved to once the last fetch completed the run without results,
+// after the last record returned, and false if it didn't
+func (c *SearchCursor) Position() (position.EntityPosition, bool) {
+	return position.EntityPosition{LastModified: c.lastModifiedTime}, c.moved
+}
+
+// FetchRecords reads the next page of the current run, or starts a new run of the query
+func (c *SearchCursor) FetchRecords(ctx context.Context) ([]sdk.Record, error) {
+	requested := time.Now()
+	c.more = false
+	c.moved = false
+	if c.done || c.nextRun.After(requested) {
+		return nil, nil
+	}
+
+	url := c.searchURL()
+	if c.afterURL != "" {
+		url = c.afterURL
+	}
+
+	p, err := c.get(ctx, url, c.afterURL != "")
+	if errors.Is(err, errExpiredAfterURL) {
+		// the run starts over, the results already read are read again
+		c.logRestart(ctx, err, c.lastModifiedTime)
+		c.afterURL = ""
+		c.resumeID = nil
+		return nil, nil
+	}
+	if err != nil || p == nil {
+		return nil, err
+	}
+
+	records, err := c.toRecords(c.resumed(p.list), c.afterURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.afterURL = ""
+	if p.Meta.HasMore && p.Links.Next != nil {
+		c.afterURL = *p.Links.Next
+	}
+	if c.afterURL == "" {
+		if err := c.completeRun(records); err != nil {
+			return nil, err
+		}
+	}
+
+	c.more = c.afterURL != ""
+	c.completePage(ctx, requested, c.afterURL == "", records)
+	return records, nil
+}
+
+// searchURL returns the url running the search query for the objects updated after the last modified time,
+// and before the end time, if set
+func (c *SearchCursor) searchURL() string {
+	query := fmt.Sprintf("%s updated>%s", c.entity.Query, c.lastModifiedTime.UTC().Format(time.RFC3339))
+	if !c.endTime.IsZero() {
+		query = fmt.Sprintf("%s updated<%s", query, c.endTime.UTC().Format(time.RFC3339))
+	}
+	params := url.Values{
+		"query":        {strings.TrimSpace(query)},
+		"filter[type]": {c.entity.ObjectType},
+	}
+	return c.entity.Endpoint + "?" + params.Encode()
+}
+
+// resumed returns the results of the page following the result the run resumes after, or every result
+// if the page doesn't have it anymore
+func (c *SearchCursor) resumed(objects []map[string]interface{}) []map[string]interface{} {
+	if c.resumeID == nil {
+		return objects
+	}
+	id := *c.resumeID
+	c.resumeID = nil
+	for i, object := range objects {
+		if object[c.entity.IDField] == id {
+			return objects[i+1:]
+		}
+	}
+	return objects
+}
+
+// toRecords converts the search results of the page to records. The results aren't ordered by update time,
+// so the records are positioned at the last modified time the run started from, and at their page in the run,
+// till the run completes.
+func (c *SearchCursor) toRecords(objects []map[string]interface{}, page string) ([]sdk.Record, error) {
+	records := make([]sdk.Record, 0, len(objects))
+	for _, object := range objects {
+		id, updatedAt, createdAt, err := c.parseObject(object, c.lastModifiedTime)
+		if err != nil {
+			return nil, err
+		}
+
+		// the search index can lag behind the objects, results out of the searched time range were or will be read
+		if !updatedAt.After(c.lastModifiedTime) || (!c.endTime.IsZero() && !updatedAt.Before(c.endTime)) {
+			continue
+		}
+		if updatedAt.After(c.runLastModified) {
+			c.runLastModified = updatedAt
+		}
+
+		pos := position.EntityPosition{
+			LastModified: c.lastModifiedTime,
+			ID:           id,
+			Search:       &position.SearchPosition{Page: page, RunLastModified: c.runLastModified},
+		}
+		record, err := c.toRecord(object, id, updatedAt, createdAt, pos)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// completeRun moves the cursor to the latest update time of the results of the completed run,
+// and positions the last record of the run there, so the next run is resumed after a restart.
+// The position moves without record if the last page of the run has none.
+func (c *SearchCursor) completeRun(records []sdk.Record) error {
+	if c.runLastModified.After(c.lastModifiedTime) {
+		c.lastModifiedTime = c.runLastModified
+	}
+	c.runLastModified = time.Time{}
+	if len(records) == 0 {
+		c.moved = true
+		return nil
+	}
+
+	last := &records[len(records)-1]
+	pos, err := position.ParsePosition(last.Position)
+	if err != nil {
+		return err
+	}
+	pos.LastModified = c.lastModifiedTime
+	pos.Search = nil
+	// the snapshot, if any, completes with the run
+	pos.SnapshotEnd = nil
+	last.Position, err = pos.ToRecordPosition()
+	return err
+}
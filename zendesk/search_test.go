@@ -0,0 +1,134 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/source/position"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCursor_FetchRecords(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/search/export.json", RawQuery: "filter%5Btype%5D=ticket&query=tags%3Avip+updated%3E1970-01-01T00%3A00%3A00Z"},
+		statusCode: 200,
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	defer testServer.Close()
+	th.resp = []byte(`{"meta":{"has_more":true},"links":{"next":"` + testServer.URL + `/api/v2/search/export.json?page%5Bafter%5D=abc"},` +
+		`"results":[{"id":2,"result_type":"ticket","updated_at":"2022-06-10T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`)
+	cursor := NewSearchCursor(newTestClient(testServer.URL, th.username, th.apiToken), SearchEntity("tags:vip", "ticket"), time.Unix(0, 0))
+
+	// the results aren't ordered, the records are positioned at the start of the run till it completes
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, map[string]string{"result_type": "ticket"}, recs[0].Metadata)
+	assert.Contains(t, string(recs[0].Position), `"last_modified_time":"1970-01-01T00:00:00Z"`)
+	// along with their page in the run, empty for the first page
+	assert.Contains(t, string(recs[0].Position), `"search":{"run_last_modified_time":"2022-06-10T05:49:55Z"}`)
+
+	th.url = &url.URL{Path: "/api/v2/search/export.json", RawQuery: "page%5Bafter%5D=abc"}
+	th.resp = []byte(`{"meta":{"has_more":false},"links":{"next":null},"results":[` +
+		`{"id":1,"result_type":"ticket","updated_at":"2022-06-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"},` +
+		`{"id":3,"result_type":"ticket","updated_at":"2022-06-09T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`)
+	recs, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 2)
+	assert.Contains(t, string(recs[0].Position), `"last_modified_time":"1970-01-01T00:00:00Z"`)
+	assert.Contains(t, string(recs[0].Position), `"page":"`+testServer.URL+`/api/v2/search/export.json?page%5Bafter%5D=abc"`)
+	assert.Contains(t, string(recs[1].Position), `"last_modified_time":"2022-06-10T05:49:55Z"`)
+	assert.NotContains(t, string(recs[1].Position), `"search"`)
+
+	// the query runs again for the objects updated after the last run
+	th.url = &url.URL{Path: "/api/v2/search/export.json", RawQuery: "filter%5Btype%5D=ticket&query=tags%3Avip+updated%3E2022-06-10T05%3A49%3A55Z"}
+	th.resp = []byte(`{"meta":{"has_more":false},"links":{"next":null},"results":[]}`)
+	recs, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+}
+
+func TestSearchCursor_Resume(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/search/export.json", RawQuery: "page%5Bafter%5D=abc"},
+		statusCode: 200,
+		resp: []byte(`{"meta":{"has_more":false},"links":{"next":null},"results":[` +
+			`{"id":1,"result_type":"ticket","updated_at":"2022-06-08T05:49:55Z","created_at":"2022-05-08T05:49:55Z"},` +
+			`{"id":3,"result_type":"ticket","updated_at":"2022-06-09T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`),
+		username: "dummy_user",
+		apiToken: "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	defer testServer.Close()
+	cursor := NewSearchCursor(newTestClient(testServer.URL, th.username, th.apiToken), SearchEntity("tags:vip", "ticket"), time.Unix(0, 0))
+
+	// the run was interrupted once the first result of the second page was read
+	cursor.Resume(position.EntityPosition{
+		LastModified: time.Unix(0, 0),
+		ID:           1,
+		Search: &position.SearchPosition{
+			Page:            testServer.URL + "/api/v2/search/export.json?page%5Bafter%5D=abc",
+			RunLastModified: time.Date(2022, 6, 10, 5, 49, 55, 0, time.UTC),
+		},
+	})
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, "3", string(recs[0].Key.Bytes()))
+	// the run completes at the latest update time of the whole run
+	assert.Contains(t, string(recs[0].Position), `"last_modified_time":"2022-06-10T05:49:55Z"`)
+}
+
+func TestSearchCursor_FetchRecords_EmptyLastPage(t *testing.T) {
+	th := &testHandler{
+		t:          t,
+		url:        &url.URL{Path: "/api/v2/search/export.json", RawQuery: "filter%5Btype%5D=ticket&query=tags%3Avip+updated%3E1970-01-01T00%3A00%3A00Z"},
+		statusCode: 200,
+		username:   "dummy_user",
+		apiToken:   "dummy_token",
+	}
+	testServer := httptest.NewServer(th)
+	defer testServer.Close()
+	th.resp = []byte(`{"meta":{"has_more":true},"links":{"next":"` + testServer.URL + `/api/v2/search/export.json?page%5Bafter%5D=abc"},` +
+		`"results":[{"id":2,"result_type":"ticket","updated_at":"2022-06-10T05:49:55Z","created_at":"2022-05-08T05:49:55Z"}]}`)
+	cursor := NewSearchCursor(newTestClient(testServer.URL, th.username, th.apiToken), SearchEntity("tags:vip", "ticket"), time.Unix(0, 0))
+
+	recs, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	_, moved := cursor.Position()
+	assert.False(t, moved)
+
+	// the last page of the run has no results, the cursor moves without record
+	th.url = &url.URL{Path: "/api/v2/search/export.json", RawQuery: "page%5Bafter%5D=abc"}
+	th.resp = []byte(`{"meta":{"has_more":false},"links":{"next":null},"results":[]}`)
+	recs, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	pos, moved := cursor.Position()
+	assert.True(t, moved)
+	assert.Equal(t, position.EntityPosition{LastModified: time.Date(2022, 6, 10, 5, 49, 55, 0, time.UTC)}, pos)
+}
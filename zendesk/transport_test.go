@@ -0,0 +1,149 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAccountClient_TLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, clientKey := writeClientCert(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "zendesk client", r.TLS.PeerCertificates[0].Subject.CommonName)
+		assert.Equal(t, uint16(tls.VersionTLS13), r.TLS.Version)
+		_, _ = w.Write([]byte(`{"tickets":[]}`))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(readFile(t, clientCert))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs} //nolint:gosec // test server
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+
+	cfg := config.Config{
+		BaseURL:  server.URL,
+		UserName: "dummy_user",
+		APIToken: "dummy_token",
+		Timeout:  time.Second,
+		TLS:      config.TLSConfig{CAFile: caFile, CertFile: clientCert, KeyFile: clientKey, MinVersion: tls.VersionTLS13},
+	}
+	client, err := NewAccountClient(cfg)
+	assert.NoError(t, err)
+	_, err = client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.NoError(t, err)
+
+	// the server certificate isn't trusted without the CA file
+	cfg.TLS.CAFile = ""
+	client, err = NewAccountClient(cfg)
+	assert.NoError(t, err)
+	_, err = client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.Error(t, err)
+}
+
+func TestNewAccountClient_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "testlab.zendesk.invalid", r.Host)
+		assert.Equal(t, "/api/v2/tickets.json", r.URL.Path)
+		_, _ = w.Write([]byte(`{"tickets":[]}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewAccountClient(config.Config{
+		BaseURL:  "http://testlab.zendesk.invalid",
+		UserName: "dummy_user",
+		APIToken: "dummy_token",
+		ProxyURL: proxy.URL,
+	})
+	assert.NoError(t, err)
+	_, err = client.Get(context.Background(), "/api/v2/tickets.json")
+	assert.NoError(t, err)
+}
+
+func TestNewTransport_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	_, err := NewTransport(config.Config{TLS: config.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}})
+	assert.ErrorContains(t, err, "could not read the CA file")
+
+	_, err = NewTransport(config.Config{TLS: config.TLSConfig{CAFile: notPEM}})
+	assert.ErrorContains(t, err, "no PEM certificate found in the CA file")
+
+	_, err = NewTransport(config.Config{TLS: config.TLSConfig{CertFile: notPEM, KeyFile: notPEM}})
+	assert.ErrorContains(t, err, "could not load the client certificate")
+}
+
+// writeClientCert writes a self signed client certificate and its key, and returns their paths
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "zendesk client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", cert)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	return content
+}
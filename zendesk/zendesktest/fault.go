@@ -0,0 +1,144 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesktest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// FaultKind is the way the server misbehaves when a fault is applied to a request
+type FaultKind int
+
+const (
+	// FaultServerError responds with the StatusCode of the fault, 500 if not set
+	FaultServerError FaultKind = iota + 1
+	// FaultTruncatedJSON responds with the status code of the request, but only the first half of the body
+	FaultTruncatedJSON
+	// FaultSlowResponse responds to the request after the Delay of the fault. Like zendesk,
+	// the request is still handled if the client gives up before, only the response is discarded.
+	FaultSlowResponse
+	// FaultExpiredCursor rejects the export requests made with a cursor with 400, as if the after_url expired.
+	// Requests made with start_time are not affected.
+	FaultExpiredCursor
+	// FaultDuplicateTickets starts the export page with the last ticket of the previous page.
+	// Requests made with start_time are not affected.
+	FaultDuplicateTickets
+	// FaultZeroTimestamps serves the exported tickets with created_at and updated_at set to 1970-01-01T00:00:00Z
+	FaultZeroTimestamps
+)
+
+const zeroTimestamp = "1970-01-01T00:00:00Z"
+
+// Fault is a misbehavior of the server, applied to the matching requests
+type Fault struct {
+	Kind       FaultKind
+	Path       string        // path of the requests the fault is applied to, every path if empty
+	Times      int           // number of requests the fault is applied to, every request if 0
+	StatusCode int           // status code of FaultServerError
+	Delay      time.Duration // delay of FaultSlowResponse
+}
+
+// Inject adds the faults, every request is affected by the first matching fault, in the order they are injected
+func (s *Server) Inject(faults ...Fault) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, f := range faults {
+		f := f
+		s.faults = append(s.faults, &f)
+	}
+}
+
+// ClearFaults removes the injected faults
+func (s *Server) ClearFaults() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.faults = nil
+}
+
+// takeFault returns the first fault matching the request, and consumes one of its times
+func (s *Server) takeFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Path != "" && f.Path != r.URL.Path {
+			continue
+		}
+		if (f.Kind == FaultExpiredCursor || f.Kind == FaultDuplicateTickets) && r.URL.Query().Get("cursor") == "" {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		fault := *f
+		return &fault
+	}
+	return nil
+}
+
+// applyFault handles the request with the fault, it returns false if the request should be handled normally.
+// The server lock is held when called, and released while a slow response waits.
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request, fault *Fault) bool {
+	switch fault.Kind {
+	case FaultServerError:
+		statusCode := fault.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusInternalServerError
+		}
+		writeJSON(w, statusCode, map[string]interface{}{"error": http.StatusText(statusCode)})
+		return true
+	case FaultTruncatedJSON:
+		rec := httptest.NewRecorder()
+		s.route(rec, r, nil)
+		for key, val := range rec.Header() {
+			w.Header()[key] = val
+		}
+		body := rec.Body.Bytes()
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(body[:len(body)/2])
+		return true
+	case FaultSlowResponse:
+		// consume the body first, so the server notices the client giving up while waiting
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return true
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		s.mux.Unlock()
+		select {
+		case <-time.After(fault.Delay):
+			s.mux.Lock()
+		case <-r.Context().Done():
+			s.mux.Lock()
+			s.route(httptest.NewRecorder(), r, nil)
+			return true
+		}
+		s.route(w, r, nil)
+		return true
+	case FaultExpiredCursor:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidPaginationParameter", "description": "cursor is invalid or expired"})
+		return true
+	default:
+		// faults changing the content of the export page
+		return false
+	}
+}
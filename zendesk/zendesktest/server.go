@@ -0,0 +1,431 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package zendesktest provides an in-memory zendesk server for tests, implementing the ticket endpoints used by the connector.
+package zendesktest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TicketsExportPath = "/api/v2/incremental/tickets/cursor.json"
+	TicketsPath       = "/api/v2/tickets.json"
+	CreateManyPath    = "/api/v2/imports/tickets/create_many"
+	JobStatusesPath   = "/api/v2/job_statuses/"
+
+	defaultPageSize = 1000 // max page size of the cursor based incremental export
+)
+
+// Server is a fake zendesk account, serving the tickets incremental cursor export, the tickets by external id,
+// the ticket bulk import and the job statuses.
+// Every ticket is stamped with a distinct updated_at second, so the second precision start_time of the export
+// never returns two tickets ambiguously.
+type Server struct {
+	*httptest.Server
+
+	mux          sync.Mutex
+	tickets      []map[string]interface{} // ordered by updated_at and id
+	jobs         map[string]map[string]interface{}
+	lastID       int64
+	lastJobID    int64
+	lastUpdated  time.Time // updated_at of the last stamped ticket, never goes back, even after Reset
+	pageSize     int
+	auth         string        // expected Authorization header, any request is allowed when empty
+	rateLimited  int           // number of upcoming requests rejected with 429
+	retryAfter   time.Duration // Retry-After sent with the injected 429 responses
+	requestCount map[string]int
+	faults       []*Fault
+	now          func() time.Time
+	active       int        // number of requests being handled
+	idle         *sync.Cond // signaled once a request is handled
+}
+
+// NewServer starts the fake zendesk server, it should be closed by the caller when done
+func NewServer() *Server {
+	s := &Server{
+		jobs:         make(map[string]map[string]interface{}),
+		pageSize:     defaultPageSize,
+		requestCount: make(map[string]int),
+		now:          time.Now,
+	}
+	s.idle = sync.NewCond(&s.mux)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// WaitIdle blocks till the requests being handled are done, including the ones the client gave up on
+func (s *Server) WaitIdle() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for s.active > 0 {
+		s.idle.Wait()
+	}
+}
+
+// SetBasicAuth makes the server reject the requests not authenticated with the username and api token
+func (s *Server) SetBasicAuth(userName, apiToken string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(userName+"/token:"+apiToken))
+}
+
+// SetPageSize sets the number of tickets returned in a page of the export, unless the request sets per_page
+func (s *Server) SetPageSize(n int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.pageSize = n
+}
+
+// RateLimit rejects the next n requests with 429, sending the retryAfter duration in the Retry-After header
+func (s *Server) RateLimit(n int, retryAfter time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.rateLimited = n
+	s.retryAfter = retryAfter
+}
+
+// RequestCount returns the number of requests received for the path, including the rejected ones
+func (s *Server) RequestCount(path string) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.requestCount[path]
+}
+
+// AddTickets stores the tickets as if they were created in zendesk, and returns the stored tickets
+func (s *Server) AddTickets(tickets ...map[string]interface{}) ([]map[string]interface{}, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	created := make([]map[string]interface{}, 0, len(tickets))
+	for _, ticket := range tickets {
+		t, err := s.create(ticket)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, t)
+	}
+	return created, nil
+}
+
+// Tickets returns a copy of the stored tickets, ordered as they are exported
+func (s *Server) Tickets() []map[string]interface{} {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	tickets := make([]map[string]interface{}, 0, len(s.tickets))
+	for _, ticket := range s.tickets {
+		tickets = append(tickets, copyTicket(ticket))
+	}
+	return tickets
+}
+
+// Reset removes the stored tickets and jobs, and the injected rate limits and faults
+func (s *Server) Reset() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.tickets = nil
+	s.jobs = make(map[string]map[string]interface{})
+	s.rateLimited = 0
+	s.faults = nil
+	s.requestCount = make(map[string]int)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.active++
+	defer func() {
+		s.active--
+		s.idle.Broadcast()
+	}()
+
+	s.requestCount[r.URL.Path]++
+	if s.auth != "" && r.Header.Get("Authorization") != s.auth {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "Couldn't authenticate you"})
+		return
+	}
+	if s.rateLimited > 0 {
+		s.rateLimited--
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(s.retryAfter.Seconds()))))
+		writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{"error": "APIRateLimitExceeded"})
+		return
+	}
+
+	fault := s.takeFault(r)
+	if fault != nil && s.applyFault(w, r, fault) {
+		return
+	}
+	s.route(w, r, fault)
+}
+
+// route handles the request with the endpoint matching its path, the fault changes the content of the export page
+func (s *Server) route(w http.ResponseWriter, r *http.Request, fault *Fault) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == TicketsExportPath:
+		s.exportTickets(w, r, fault)
+	case r.Method == http.MethodGet && r.URL.Path == TicketsPath:
+		s.listTickets(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == CreateManyPath:
+		s.createMany(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, JobStatusesPath):
+		s.jobStatus(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "InvalidEndpoint"})
+	}
+}
+
+// exportTickets serves a page of the cursor based incremental export, starting either from start_time, or after the cursor
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/ticket-management/incremental_exports/#incremental-ticket-export-cursor-based
+func (s *Server) exportTickets(w http.ResponseWriter, r *http.Request, fault *Fault) {
+	query := r.URL.Query()
+	pageSize := s.pageSize
+	if perPage := query.Get("per_page"); perPage != "" {
+		n, err := strconv.Atoi(perPage)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidPaginationParameter"})
+			return
+		}
+		pageSize = n
+	}
+
+	// index of the first ticket of the page
+	start := 0
+	switch {
+	case query.Get("cursor") != "":
+		updatedAt, id, err := parseCursor(query.Get("cursor"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidCursor"})
+			return
+		}
+		start = sort.Search(len(s.tickets), func(i int) bool {
+			u, tid := ticketOrder(s.tickets[i])
+			return u > updatedAt || (u == updatedAt && tid > id)
+		})
+	case query.Get("start_time") != "":
+		startTime, err := strconv.ParseInt(query.Get("start_time"), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidValue"})
+			return
+		}
+		start = sort.Search(len(s.tickets), func(i int) bool {
+			u, _ := ticketOrder(s.tickets[i])
+			return u >= startTime
+		})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidValue", "description": "start_time or cursor is required"})
+		return
+	}
+
+	end := start + pageSize
+	if end > len(s.tickets) {
+		end = len(s.tickets)
+	}
+	first := start
+	if fault != nil && fault.Kind == FaultDuplicateTickets && first > 0 {
+		first--
+	}
+	page := make([]map[string]interface{}, 0, end-first)
+	for _, ticket := range s.tickets[first:end] {
+		page = append(page, copyTicket(ticket))
+	}
+
+	// the cursor of an empty page stays where the request started, so new tickets are picked up by the next request
+	cursor := query.Get("cursor")
+	if len(page) > 0 {
+		updatedAt, id := ticketOrder(page[len(page)-1])
+		cursor = formatCursor(updatedAt, id)
+	} else if cursor == "" {
+		startTime, _ := strconv.ParseInt(query.Get("start_time"), 10, 64)
+		cursor = formatCursor(startTime-1, math.MaxInt64)
+	}
+	afterURL := fmt.Sprintf("%s%s?cursor=%s", s.URL, TicketsExportPath, cursor)
+	if query.Get("per_page") != "" {
+		afterURL += "&per_page=" + query.Get("per_page")
+	}
+
+	if fault != nil && fault.Kind == FaultZeroTimestamps {
+		for _, ticket := range page {
+			ticket["created_at"] = zeroTimestamp
+			ticket["updated_at"] = zeroTimestamp
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tickets":       page,
+		"after_url":     afterURL,
+		"after_cursor":  cursor,
+		"before_url":    nil,
+		"before_cursor": nil,
+		"end_of_stream": end == len(s.tickets),
+	})
+}
+
+// listTickets serves the tickets with the external_id of the request, which is required
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/tickets/tickets/#list-tickets
+func (s *Server) listTickets(w http.ResponseWriter, r *http.Request) {
+	externalID := r.URL.Query().Get("external_id")
+	if externalID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidValue", "description": "external_id is required"})
+		return
+	}
+	tickets := make([]map[string]interface{}, 0)
+	for _, ticket := range s.tickets {
+		if ticket["external_id"] == externalID {
+			tickets = append(tickets, copyTicket(ticket))
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tickets": tickets, "count": len(tickets)})
+}
+
+// createMany imports the tickets, the returned job is completed right away
+// NOTE: https://developer.zendesk.com/api-reference/ticketing/tickets/ticket_import/#ticket-bulk-import
+func (s *Server) createMany(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tickets []map[string]interface{} `json:"tickets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidJSON", "description": err.Error()})
+		return
+	}
+	if len(req.Tickets) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "InvalidValue", "description": "at most 100 tickets can be imported at once"})
+		return
+	}
+
+	results := make([]map[string]interface{}, 0, len(req.Tickets))
+	for i, ticket := range req.Tickets {
+		created, err := s.create(ticket)
+		if err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": "RecordInvalid", "description": err.Error()})
+			return
+		}
+		results = append(results, map[string]interface{}{"index": i, "id": created["id"]})
+	}
+
+	s.lastJobID++
+	id := fmt.Sprintf("%032x", s.lastJobID)
+	job := map[string]interface{}{
+		"id":       id,
+		"url":      fmt.Sprintf("%s%s%s.json", s.URL, JobStatusesPath, id),
+		"total":    len(req.Tickets),
+		"progress": len(req.Tickets),
+		"status":   "completed",
+		"message":  nil,
+		"results":  results,
+	}
+	s.jobs[id] = job
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_status": job})
+}
+
+// jobStatus serves the status of the job created by a bulk import
+func (s *Server) jobStatus(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, JobStatusesPath), ".json")
+	job, ok := s.jobs[id]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "RecordNotFound"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_status": job})
+}
+
+// create stamps the ticket with an id and the next updated_at second, and stores it.
+// The ticket goes through JSON, so the stored ticket holds the same types as a ticket decoded from the export.
+func (s *Server) create(ticket map[string]interface{}) (map[string]interface{}, error) {
+	stamp := s.now().UTC().Truncate(time.Second)
+	if !stamp.After(s.lastUpdated) {
+		stamp = s.lastUpdated.Add(time.Second)
+	}
+	s.lastUpdated = stamp
+	s.lastID++
+
+	t := make(map[string]interface{}, len(ticket)+5)
+	for k, v := range ticket {
+		t[k] = v
+	}
+	t["id"] = s.lastID
+	t["url"] = fmt.Sprintf("%s/api/v2/tickets/%d.json", s.URL, s.lastID)
+	t["created_at"] = stamp.Format(time.RFC3339)
+	t["updated_at"] = stamp.Format(time.RFC3339)
+	if _, ok := t["status"]; !ok {
+		t["status"] = "new"
+	}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ticket: %w", err)
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("invalid ticket: %w", err)
+	}
+	s.tickets = append(s.tickets, stored)
+	return copyTicket(stored), nil
+}
+
+// ticketOrder returns the updated_at unix time and the id, by which the tickets are exported
+func ticketOrder(ticket map[string]interface{}) (int64, int64) {
+	updatedAt, _ := time.Parse(time.RFC3339, ticket["updated_at"].(string))
+	return updatedAt.Unix(), int64(ticket["id"].(float64))
+}
+
+func formatCursor(updatedAt, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", updatedAt, id)))
+}
+
+func parseCursor(cursor string) (int64, int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, err
+	}
+	parts := strings.SplitN(string(b), ".", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	updatedAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return updatedAt, id, nil
+}
+
+func copyTicket(ticket map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(ticket))
+	for k, v := range ticket {
+		c[k] = v
+	}
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
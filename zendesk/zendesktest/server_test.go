@@ -0,0 +1,219 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zendesktest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-zendesk/zendesk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_CreateManyAndJobStatus(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetBasicAuth("dummy_user", "dummy_token")
+
+	client := zendesk.NewClient(server.URL, zendesk.BasicAuth("dummy_user", "dummy_token"))
+	body, err := client.Post(context.Background(), CreateManyPath, []byte(`{"tickets":[{"subject":"first"},{"subject":"second","status":"open"}]}`))
+	assert.NoError(t, err)
+
+	var res struct {
+		JobStatus struct {
+			ID      string `json:"id"`
+			URL     string `json:"url"`
+			Status  string `json:"status"`
+			Results []struct {
+				Index int   `json:"index"`
+				ID    int64 `json:"id"`
+			} `json:"results"`
+		} `json:"job_status"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &res))
+	assert.Equal(t, "completed", res.JobStatus.Status)
+	assert.Len(t, res.JobStatus.Results, 2)
+	assert.Equal(t, int64(2), res.JobStatus.Results[1].ID)
+
+	body, err = client.Get(context.Background(), res.JobStatus.URL)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), res.JobStatus.ID)
+
+	tickets := server.Tickets()
+	assert.Len(t, tickets, 2)
+	assert.Equal(t, "first", tickets[0]["subject"])
+	assert.Equal(t, "new", tickets[0]["status"])
+	assert.Equal(t, "open", tickets[1]["status"])
+	assert.NotEqual(t, tickets[0]["updated_at"], tickets[1]["updated_at"])
+
+	// requests with other credentials are rejected
+	_, err = zendesk.NewClient(server.URL, zendesk.BasicAuth("dummy_user", "wrong_token")).Get(context.Background(), res.JobStatus.URL)
+	var statusErr *zendesk.StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, 401, statusErr.StatusCode)
+}
+
+func TestServer_ExportPagination(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetPageSize(2)
+
+	tickets := make([]map[string]interface{}, 0, 5)
+	for i := 0; i < 5; i++ {
+		tickets = append(tickets, map[string]interface{}{"subject": fmt.Sprintf("ticket %d", i)})
+	}
+	_, err := server.AddTickets(tickets...)
+	assert.NoError(t, err)
+
+	cursor := zendesk.NewCursor(zendesk.NewClient(server.URL), zendesk.Tickets, time.Unix(0, 0))
+	cursor.StartSnapshot(time.Now().Add(time.Hour))
+
+	var keys []string
+	for i := 0; i < 3; i++ {
+		records, err := cursor.FetchRecords(context.Background())
+		assert.NoError(t, err)
+		for _, r := range records {
+			keys = append(keys, string(r.Key.Bytes()))
+		}
+		// the snapshot completes with the last page
+		if i == 2 {
+			assert.Equal(t, "true", records[len(records)-1].Metadata[zendesk.MetadataSnapshotCompleted])
+		}
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, keys)
+
+	// the after url of the last page picks up the tickets created later
+	records, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	_, err = server.AddTickets(map[string]interface{}{"subject": "later"})
+	assert.NoError(t, err)
+	records, err = cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "6", string(records[0].Key.Bytes()))
+}
+
+func TestServer_ExportStartTime(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	created, err := server.AddTickets(map[string]interface{}{"subject": "first"}, map[string]interface{}{"subject": "second"})
+	assert.NoError(t, err)
+	firstUpdated, err := time.Parse(time.RFC3339, created[0]["updated_at"].(string))
+	assert.NoError(t, err)
+
+	// the cursor restarts one second after the last modified time, only the second ticket is exported
+	cursor := zendesk.NewCursor(zendesk.NewClient(server.URL), zendesk.Tickets, firstUpdated)
+	records, err := cursor.FetchRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "2", string(records[0].Key.Bytes()))
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.RateLimit(1, 1500*time.Millisecond)
+
+	client := zendesk.NewClient(server.URL)
+	_, err := client.Get(context.Background(), TicketsExportPath+"?start_time=0")
+	var rateLimitErr *zendesk.RateLimitError
+	assert.True(t, errors.As(err, &rateLimitErr))
+	assert.Equal(t, 2*time.Second, rateLimitErr.RetryAfter)
+
+	_, err = client.Get(context.Background(), TicketsExportPath+"?start_time=0")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.RequestCount(TicketsExportPath))
+}
+
+func TestServer_Reset(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	created, err := server.AddTickets(map[string]interface{}{"subject": "first"})
+	assert.NoError(t, err)
+	server.Reset()
+	assert.Empty(t, server.Tickets())
+
+	// timestamps never go back, so positions recorded before the reset stay valid
+	recreated, err := server.AddTickets(map[string]interface{}{"subject": "first"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, created[0]["updated_at"], recreated[0]["updated_at"])
+}
+
+func TestServer_Inject(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	_, err := server.AddTickets(map[string]interface{}{"subject": "first"}, map[string]interface{}{"subject": "second"})
+	assert.NoError(t, err)
+	server.SetPageSize(1)
+	server.Inject(
+		Fault{Kind: FaultExpiredCursor, Times: 1},
+		Fault{Kind: FaultServerError, Path: CreateManyPath, StatusCode: 503, Times: 1},
+		Fault{Kind: FaultTruncatedJSON, Path: TicketsExportPath, Times: 1},
+	)
+
+	client := zendesk.NewClient(server.URL)
+	var statusErr *zendesk.StatusError
+
+	// the expired cursor fault doesn't apply to start_time requests, the truncated json does
+	body, err := client.Get(context.Background(), TicketsExportPath+"?start_time=0")
+	assert.NoError(t, err)
+	assert.False(t, json.Valid(body))
+
+	body, err = client.Get(context.Background(), TicketsExportPath+"?start_time=0")
+	assert.NoError(t, err)
+	var page struct {
+		AfterURL string `json:"after_url"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &page))
+
+	_, err = client.Get(context.Background(), page.AfterURL)
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, 400, statusErr.StatusCode)
+
+	_, err = client.Post(context.Background(), CreateManyPath, []byte(`{"tickets":[{"subject":"third"}]}`))
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, 503, statusErr.StatusCode)
+
+	// every fault is consumed
+	_, err = client.Get(context.Background(), page.AfterURL)
+	assert.NoError(t, err)
+	_, err = client.Post(context.Background(), CreateManyPath, []byte(`{"tickets":[{"subject":"third"}]}`))
+	assert.NoError(t, err)
+	assert.Len(t, server.Tickets(), 3)
+}
+
+func TestServer_SlowResponseAbandoned(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Inject(Fault{Kind: FaultSlowResponse, Delay: 200 * time.Millisecond, Times: 1})
+
+	client := zendesk.NewClient(server.URL, zendesk.Timeout(50*time.Millisecond))
+	_, err := client.Post(context.Background(), CreateManyPath, []byte(`{"tickets":[{"subject":"first"}]}`))
+	assert.Error(t, err)
+
+	// the request is still handled once the client gave up
+	server.Close()
+	assert.Len(t, server.Tickets(), 1)
+}